@@ -0,0 +1,145 @@
+package iwd
+
+import (
+	"context"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/shtirlic/go-iwd/utils"
+)
+
+const (
+	iwdAccessPointIface           = iwdService + ".AccessPoint"
+	iwdAccessPointDiagnosticIface = iwdService + ".AccessPointDiagnostic"
+
+	callAccessPointStart                    = iwdAccessPointIface + ".Start"
+	callAccessPointStartProfile             = iwdAccessPointIface + ".StartProfile"
+	callAccessPointStop                     = iwdAccessPointIface + ".Stop"
+	callAccessPointDiagnosticGetDiagnostics = iwdAccessPointDiagnosticIface + ".GetDiagnostics"
+)
+
+type AccessPoint struct {
+	Path            dbus.ObjectPath `iwd:"-"` // /net/connman/iwd/{phy0,phy1,...}/{1,2,...}
+	Started         bool            // [ro] Reflects whether an access point has been started.
+	Name            string          // [ro] The current SSID name, if started
+	Frequency       uint32          // [ro] The frequency that the access point is operating on, if started
+	PairwiseCiphers []string        // [ro] The list of pairwise ciphers the access point supports, if started
+	GroupCipher     string          // [ro] The group cipher the access point is using, if started
+	object
+}
+
+type AccessPointClientInfo struct {
+	Address       string // MAC address of the connected station.
+	RSSI          int    // The RSSI of the connected station.
+	AverageRSSI   int    // Average RSSI of the connected station.
+	RxRate        int    // Receive rate in 100kbit/s
+	RxBitrate     int    // Receive rate in 100kbit/s
+	RxMCS         int    // Receiving MCS index
+	RxMode        string // The phy technology being used (802.11n, 802.11ac or 802.11ax).
+	TxRate        int    // Transmission rate in 100kbit/s
+	TxBitrate     int    // Transmission rate in 100kbit/s
+	TxMCS         int    // Transmitting MCS index
+	TxMode        string // Same meaning as RxMode, just for transmission.
+	InactiveTime  int    // Time duration (in ms) for which the station has been inactive.
+	ConnectedTime int    // Time duration (in s) for which the station has been connected.
+}
+
+func NewAccessPoint(p dbus.ObjectPath, i *Iwd) (*AccessPoint, error) {
+	return NewAccessPointContext(context.Background(), p, i)
+}
+
+// NewAccessPointContext is the context-aware variant of NewAccessPoint.
+func NewAccessPointContext(ctx context.Context, p dbus.ObjectPath, i *Iwd) (*AccessPoint, error) {
+	return newAccessPoint(ctx, p, i, liveSource{i})
+}
+
+func newAccessPoint(ctx context.Context, p dbus.ObjectPath, i *Iwd, src propertySource) (*AccessPoint, error) {
+	objects, err := src.properties(ctx, p, iwdAccessPointIface)
+	if err != nil {
+		return nil, err
+	}
+	ap := &AccessPoint{
+		Path:   p,
+		object: i.object(),
+	}
+	if err := decodeProperties(objects, p, iwdAccessPointIface, ap); err != nil {
+		return nil, err
+	}
+	return ap, nil
+}
+
+// Start an access point called ssid with a passphrase
+// of psk.
+func (a *AccessPoint) Start(ssid, psk string) error {
+	return a.StartContext(context.Background(), ssid, psk)
+}
+
+// StartContext is the context-aware variant of Start.
+func (a *AccessPoint) StartContext(ctx context.Context, ssid, psk string) error {
+	if _, err := a.callMethod(ctx, a.Path, callAccessPointStart, ssid, psk); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Start an access point called ssid.  Any additional
+// settings are taken from the access point profile
+// stored in the iwd AP configuration directory under
+// the name <ssid>.ap.  If no profile exists for ssid,
+// an net.connman.iwd.NotFound error is returned.
+func (a *AccessPoint) StartProfile(ssid string) error {
+	return a.StartProfileContext(context.Background(), ssid)
+}
+
+// StartProfileContext is the context-aware variant of StartProfile.
+func (a *AccessPoint) StartProfileContext(ctx context.Context, ssid string) error {
+	if _, err := a.callMethod(ctx, a.Path, callAccessPointStartProfile, ssid); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Stop a started access point.  Note: Calling Stop()
+// will not bring the interface down; the device stays
+// in the "ap" mode until its Mode is changed.
+func (a *AccessPoint) Stop() error {
+	return a.StopContext(context.Background())
+}
+
+// StopContext is the context-aware variant of Stop.
+func (a *AccessPoint) StopContext(ctx context.Context) error {
+	if _, err := a.callMethod(ctx, a.Path, callAccessPointStop); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Get all diagnostic information for this interface. The
+// diagnostics are contained in a list of dictionaries, one
+// per station connected to the access point. Values here
+// are generally low level and not meant for general
+// purpose applications. The values in the dictionaries
+// may come and go depending on the state of IWD.
+func (a *AccessPoint) GetDiagnostics() ([]AccessPointClientInfo, error) {
+	return a.GetDiagnosticsContext(context.Background())
+}
+
+// GetDiagnosticsContext is the context-aware variant of GetDiagnostics.
+func (a *AccessPoint) GetDiagnosticsContext(ctx context.Context) ([]AccessPointClientInfo, error) {
+	call, err := a.callMethod(ctx, a.Path, callAccessPointDiagnosticGetDiagnostics)
+	if err != nil {
+		return nil, err
+	}
+	var objects []utils.DBusMapVariant
+	if err := call.Store(&objects); err != nil {
+		return nil, err
+	}
+	var clients []AccessPointClientInfo
+	for _, o := range objects {
+		var client AccessPointClientInfo
+		if err := utils.Decode(o, &client); err != nil {
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+	return clients, nil
+}
@@ -1,8 +1,9 @@
 package iwd
 
 import (
+	"context"
+
 	"github.com/godbus/dbus/v5"
-	"github.com/shtirlic/go-iwd/utils"
 )
 
 const (
@@ -18,31 +19,67 @@ const (
 )
 
 type Adapter struct {
-	Path           dbus.ObjectPath // /net/connman/iwd/{phy0,phy1,...}
+	Path           dbus.ObjectPath `iwd:"-"` // /net/connman/iwd/{phy0,phy1,...}
 	Name           string          // [ro] Contains the name of the adapter
 	Model          string          // [ro] Contains the model name of the adapter, if available
 	Vendor         string          // [ro] Contains the vendor name of the adapter, if available
 	Powered        bool            // [rw]
 	SupportedModes []DeviceMode    // [ro] Contains the supported modes for this adapter's devices
-	iwd            *Iwd
+	object
 }
 
 func NewAdapter(p dbus.ObjectPath, i *Iwd) (*Adapter, error) {
-	objects, err := utils.GetAllProperties(i.conn, iwdService, p, iwdAdapterIface)
+	return NewAdapterContext(context.Background(), p, i)
+}
+
+// NewAdapterContext is the context-aware variant of NewAdapter.
+func NewAdapterContext(ctx context.Context, p dbus.ObjectPath, i *Iwd) (*Adapter, error) {
+	return newAdapter(ctx, p, i, liveSource{i})
+}
+
+func newAdapter(ctx context.Context, p dbus.ObjectPath, i *Iwd, src propertySource) (*Adapter, error) {
+	objects, err := src.properties(ctx, p, iwdAdapterIface)
 	if err != nil {
 		return nil, err
 	}
-	var modes []DeviceMode
-	for _, m := range objects["SupportedModes"].Value().([]string) {
-		modes = append(modes, DeviceMode(m))
+	adapter := &Adapter{
+		Path:   p,
+		object: i.object(),
+	}
+	if err := decodeProperties(objects, p, iwdAdapterIface, adapter); err != nil {
+		return nil, err
+	}
+	return adapter, nil
+}
+
+// Reload the properties of the adapter from iwd in place.
+// The returned error matches ErrUnknownObject if the
+// adapter has disappeared since.  Refresh writes the
+// fields, so the adapter must not be used concurrently.
+func (a *Adapter) Refresh() error {
+	return a.RefreshContext(context.Background())
+}
+
+// RefreshContext is the context-aware variant of Refresh.
+func (a *Adapter) RefreshContext(ctx context.Context) error {
+	fresh, err := NewAdapterContext(ctx, a.Path, a.iwd)
+	if err != nil {
+		return err
+	}
+	*a = *fresh
+	return nil
+}
+
+// Power the adapter on or off.
+func (a *Adapter) SetPowered(powered bool) error {
+	return a.SetPoweredContext(context.Background(), powered)
+}
+
+// SetPoweredContext is the context-aware variant of SetPowered.
+func (a *Adapter) SetPoweredContext(ctx context.Context, powered bool) error {
+	if err := a.setProperty(ctx, a.Path, iwdAdapterIface, "Powered", powered); err != nil {
+		return err
 	}
-	return &Adapter{
-		Path:           p,
-		Model:          objects["Model"].Value().(string),
-		Name:           objects["Name"].Value().(string),
-		Powered:        objects["Powered"].Value().(bool),
-		SupportedModes: modes,
-		Vendor:         objects["Vendor"].Value().(string),
-		iwd:            i,
-	}, nil
+	a.Powered = powered
+	return nil
 }
@@ -0,0 +1,87 @@
+package iwd
+
+import (
+	"context"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	iwdAdHocIface = iwdService + ".AdHoc"
+
+	callAdHocStart     = iwdAdHocIface + ".Start"
+	callAdHocStartOpen = iwdAdHocIface + ".StartOpen"
+	callAdHocStop      = iwdAdHocIface + ".Stop"
+)
+
+type AdHoc struct {
+	Path           dbus.ObjectPath `iwd:"-"` // /net/connman/iwd/{phy0,phy1,...}/{1,2,...}
+	Started        bool            // [ro] Reflects whether the IBSS network has been started.
+	ConnectedPeers []string        // [ro] Hardware addresses of the peers currently connected to the IBSS network
+	object
+}
+
+func NewAdHoc(p dbus.ObjectPath, i *Iwd) (*AdHoc, error) {
+	return NewAdHocContext(context.Background(), p, i)
+}
+
+// NewAdHocContext is the context-aware variant of NewAdHoc.
+func NewAdHocContext(ctx context.Context, p dbus.ObjectPath, i *Iwd) (*AdHoc, error) {
+	return newAdHoc(ctx, p, i, liveSource{i})
+}
+
+func newAdHoc(ctx context.Context, p dbus.ObjectPath, i *Iwd, src propertySource) (*AdHoc, error) {
+	objects, err := src.properties(ctx, p, iwdAdHocIface)
+	if err != nil {
+		return nil, err
+	}
+	adhoc := &AdHoc{
+		Path:   p,
+		object: i.object(),
+	}
+	if err := decodeProperties(objects, p, iwdAdHocIface, adhoc); err != nil {
+		return nil, err
+	}
+	return adhoc, nil
+}
+
+// Start or join an Ad-Hoc (IBSS) network called ssid,
+// secured with the passphrase psk.
+func (a *AdHoc) Start(ssid, psk string) error {
+	return a.StartContext(context.Background(), ssid, psk)
+}
+
+// StartContext is the context-aware variant of Start.
+func (a *AdHoc) StartContext(ctx context.Context, ssid, psk string) error {
+	if _, err := a.callMethod(ctx, a.Path, callAdHocStart, ssid, psk); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Start or join an open Ad-Hoc (IBSS) network called
+// ssid.
+func (a *AdHoc) StartOpen(ssid string) error {
+	return a.StartOpenContext(context.Background(), ssid)
+}
+
+// StartOpenContext is the context-aware variant of StartOpen.
+func (a *AdHoc) StartOpenContext(ctx context.Context, ssid string) error {
+	if _, err := a.callMethod(ctx, a.Path, callAdHocStartOpen, ssid); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Leave the Ad-Hoc network.
+func (a *AdHoc) Stop() error {
+	return a.StopContext(context.Background())
+}
+
+// StopContext is the context-aware variant of Stop.
+func (a *AdHoc) StopContext(ctx context.Context) error {
+	if _, err := a.callMethod(ctx, a.Path, callAdHocStop); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package iwd
+
+import (
+	"context"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	iwdAgentIface        = iwdService + ".Agent"
+	iwdAgentManagerIface = iwdService + ".AgentManager"
+
+	callAgentManagerRegisterAgent   = iwdAgentManagerIface + ".RegisterAgent"
+	callAgentManagerUnregisterAgent = iwdAgentManagerIface + ".UnregisterAgent"
+
+	agentErrorCanceled = iwdAgentIface + ".Error.Canceled"
+)
+
+// Reason passed to Agent.Cancel.  One of:
+// "out-of-range", "user-canceled", "timed-out",
+// "shutdown"
+type AgentCancelReason string
+
+const (
+	OutOfRangeCancelReason   AgentCancelReason = "out-of-range"
+	UserCanceledCancelReason AgentCancelReason = "user-canceled"
+	TimedOutCancelReason     AgentCancelReason = "timed-out"
+	ShutdownCancelReason     AgentCancelReason = "shutdown"
+)
+
+// Agent is implemented by applications that provide
+// credentials to iwd when connecting to a network.
+// Returning a non-nil error from any of the Request
+// methods makes iwd cancel the connection attempt.
+type Agent interface {
+	// This method gets called when the service daemon
+	// unregisters the agent.  An agent can use it to do
+	// cleanup tasks.  There is no need to unregister the
+	// agent, because when this method gets called it has
+	// already been unregistered.
+	Release()
+
+	// This method gets called when trying to connect to
+	// a network and passphrase is required.
+	RequestPassphrase(network *Network) (string, error)
+
+	// This method gets called when connecting to
+	// a network that requires authentication using a
+	// locally-stored encrypted private key file, to
+	// obtain that private key's encryption passphrase.
+	RequestPrivateKeyPassphrase(network *Network) (string, error)
+
+	// This method gets called when connecting to
+	// a network that requires authentication using a
+	// user name and password.
+	RequestUserNameAndPassword(network *Network) (string, string, error)
+
+	// This method gets called when connecting to
+	// a network that requires authentication with a
+	// user password.  The user name is optionally passed
+	// in the parameter.
+	RequestUserPassword(network *Network, user string) (string, error)
+
+	// This method gets called to indicate that the agent
+	// request failed before a reply was returned.
+	Cancel(reason AgentCancelReason)
+}
+
+// agentExport adapts an Agent to the method set exported
+// on the D-Bus connection.
+type agentExport struct {
+	agent Agent
+	path  dbus.ObjectPath
+	iwd   *Iwd
+}
+
+func agentError(err error) *dbus.Error {
+	return dbus.NewError(agentErrorCanceled, []interface{}{err.Error()})
+}
+
+func (a *agentExport) Release() *dbus.Error {
+	a.iwd.recordAgentCall(a.path, iwdAgentIface+".Release", nil, nil, nil)
+	a.agent.Release()
+	return nil
+}
+
+func (a *agentExport) RequestPassphrase(p dbus.ObjectPath) (passphrase string, dbusErr *dbus.Error) {
+	defer func() {
+		a.iwd.recordAgentCall(a.path, iwdAgentIface+".RequestPassphrase", []interface{}{p},
+			[]interface{}{passphrase}, dbusErr)
+	}()
+	network, err := NewNetwork(p, a.iwd)
+	if err != nil {
+		return "", agentError(err)
+	}
+	passphrase, err = a.agent.RequestPassphrase(network)
+	if err != nil {
+		return "", agentError(err)
+	}
+	return passphrase, nil
+}
+
+func (a *agentExport) RequestPrivateKeyPassphrase(p dbus.ObjectPath) (passphrase string, dbusErr *dbus.Error) {
+	defer func() {
+		a.iwd.recordAgentCall(a.path, iwdAgentIface+".RequestPrivateKeyPassphrase", []interface{}{p},
+			[]interface{}{passphrase}, dbusErr)
+	}()
+	network, err := NewNetwork(p, a.iwd)
+	if err != nil {
+		return "", agentError(err)
+	}
+	passphrase, err = a.agent.RequestPrivateKeyPassphrase(network)
+	if err != nil {
+		return "", agentError(err)
+	}
+	return passphrase, nil
+}
+
+func (a *agentExport) RequestUserNameAndPassword(p dbus.ObjectPath) (user, password string, dbusErr *dbus.Error) {
+	defer func() {
+		a.iwd.recordAgentCall(a.path, iwdAgentIface+".RequestUserNameAndPassword", []interface{}{p},
+			[]interface{}{user, password}, dbusErr)
+	}()
+	network, err := NewNetwork(p, a.iwd)
+	if err != nil {
+		return "", "", agentError(err)
+	}
+	user, password, err = a.agent.RequestUserNameAndPassword(network)
+	if err != nil {
+		return "", "", agentError(err)
+	}
+	return user, password, nil
+}
+
+func (a *agentExport) RequestUserPassword(p dbus.ObjectPath, user string) (password string, dbusErr *dbus.Error) {
+	defer func() {
+		a.iwd.recordAgentCall(a.path, iwdAgentIface+".RequestUserPassword", []interface{}{p, user},
+			[]interface{}{password}, dbusErr)
+	}()
+	network, err := NewNetwork(p, a.iwd)
+	if err != nil {
+		return "", agentError(err)
+	}
+	password, err = a.agent.RequestUserPassword(network, user)
+	if err != nil {
+		return "", agentError(err)
+	}
+	return password, nil
+}
+
+func (a *agentExport) Cancel(reason string) *dbus.Error {
+	a.iwd.recordAgentCall(a.path, iwdAgentIface+".Cancel", []interface{}{reason}, nil, nil)
+	a.agent.Cancel(AgentCancelReason(reason))
+	return nil
+}
+
+// Register new agent for handling user requests.  The
+// agent is exported at path on the library's D-Bus
+// connection and then registered with iwd.  If iwd
+// restarts, the agent is registered again once iwd is
+// back.
+func (i *Iwd) RegisterAgent(path dbus.ObjectPath, agent Agent) error {
+	return i.RegisterAgentContext(context.Background(), path, agent)
+}
+
+// RegisterAgentContext is the context-aware variant of RegisterAgent.
+func (i *Iwd) RegisterAgentContext(ctx context.Context, path dbus.ObjectPath, agent Agent) error {
+	if err := i.backend.Export(&agentExport{agent: agent, path: path, iwd: i}, path, iwdAgentIface); err != nil {
+		return err
+	}
+	// The agent is tracked before it is registered, so that
+	// it is registered again if iwd restarts meanwhile.
+	i.agentMu.Lock()
+	i.agents[path] = struct{}{}
+	i.agentMu.Unlock()
+	if _, err := i.CallServiceMethodContext(ctx, iwdObjPath, callAgentManagerRegisterAgent, path); err != nil {
+		i.agentMu.Lock()
+		delete(i.agents, path)
+		i.agentMu.Unlock()
+		i.backend.Export(nil, path, iwdAgentIface)
+		return err
+	}
+	return nil
+}
+
+// Unregister an existing agent and stop exporting it
+// on the library's D-Bus connection.
+func (i *Iwd) UnregisterAgent(path dbus.ObjectPath) error {
+	return i.UnregisterAgentContext(context.Background(), path)
+}
+
+// UnregisterAgentContext is the context-aware variant of UnregisterAgent.
+func (i *Iwd) UnregisterAgentContext(ctx context.Context, path dbus.ObjectPath) error {
+	defer i.backend.Export(nil, path, iwdAgentIface)
+	i.agentMu.Lock()
+	delete(i.agents, path)
+	i.agentMu.Unlock()
+	if _, err := i.CallServiceMethodContext(ctx, iwdObjPath, callAgentManagerUnregisterAgent, path); err != nil {
+		return err
+	}
+	return nil
+}
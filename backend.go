@@ -0,0 +1,188 @@
+package iwd
+
+import (
+	"context"
+	"errors"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/shtirlic/go-iwd/utils"
+)
+
+// Backend carries the traffic between Iwd and the iwd
+// service.  The default backend talks to iwd over a godbus
+// connection, see NewConnBackend; iwdtest provides an
+// in-memory one for tests.
+//
+// Values follow the godbus conventions: replies and signal
+// bodies hold the types godbus decodes messages into, e.g.
+// structs as []interface{}.  All methods must be safe for
+// concurrent use.
+type Backend interface {
+	// CallMethod calls method, given as interface and
+	// member, on the iwd object at path.
+	CallMethod(ctx context.Context, path dbus.ObjectPath, method string, args ...interface{}) (*dbus.Call, error)
+
+	// GetProperty returns the property name of iface on
+	// the iwd object at path.
+	GetProperty(ctx context.Context, path dbus.ObjectPath, iface, name string) (dbus.Variant, error)
+
+	// GetAllProperties returns every property of iface on
+	// the iwd object at path.
+	GetAllProperties(ctx context.Context, path dbus.ObjectPath, iface string) (utils.DBusMapVariant, error)
+
+	// SetProperty sets the property name of iface on the
+	// iwd object at path.
+	SetProperty(ctx context.Context, path dbus.ObjectPath, iface, name string, value interface{}) error
+
+	// GetManagedObjects returns the properties of every
+	// interface of every iwd object.
+	GetManagedObjects(ctx context.Context) (utils.DBusRetValues, error)
+
+	// AddSignalMatch subscribes to the signal member of
+	// iface emitted by iwd on path, or on any path if path
+	// is empty.  Subscriptions are counted, so a signal
+	// matched twice is removed with two calls to
+	// RemoveSignalMatch.
+	AddSignalMatch(path dbus.ObjectPath, iface, member string) error
+
+	// RemoveSignalMatch cancels a subscription made with
+	// AddSignalMatch.
+	RemoveSignalMatch(path dbus.ObjectPath, iface, member string) error
+
+	// Signal delivers the subscribed signals to ch.  It is
+	// called once per Iwd; ch must not block delivery for
+	// long.
+	Signal(ch chan<- *dbus.Signal)
+
+	// WatchService reports the unique bus name of the
+	// owner of the iwd service to ch: first the current
+	// one, which must be sent before WatchService returns,
+	// then every change, with an empty name while iwd is
+	// not running.  ch has room for the first name.
+	// Signal subscriptions must stay in effect while iwd
+	// restarts.  ch is closed when the backend is closed.
+	// It is called once per Iwd, from the constructor.
+	WatchService(ch chan<- string) error
+
+	// Export makes the methods of v callable by iwd as
+	// iface on path, as dbus.Conn.Export does.  A nil v
+	// stops exporting.
+	Export(v interface{}, path dbus.ObjectPath, iface string) error
+
+	// Close releases the backend.
+	Close() error
+}
+
+// connBackend is the Backend talking to iwd over a godbus
+// connection.
+type connBackend struct {
+	conn *dbus.Conn
+}
+
+// NewConnBackend returns the Backend that talks to iwd
+// over conn.
+func NewConnBackend(conn *dbus.Conn) Backend {
+	return connBackend{conn}
+}
+
+func (b connBackend) CallMethod(ctx context.Context, path dbus.ObjectPath, method string,
+	args ...interface{}) (*dbus.Call, error) {
+
+	return utils.CallMethodContext(ctx, b.conn, iwdService, path, method, args...)
+}
+
+func (b connBackend) GetProperty(ctx context.Context, path dbus.ObjectPath, iface, name string) (dbus.Variant, error) {
+	call, err := utils.CallMethodContext(ctx, b.conn, iwdService, path, callPropertiesGet, iface, name)
+	if err != nil {
+		return dbus.Variant{}, err
+	}
+	var v dbus.Variant
+	if err := call.Store(&v); err != nil {
+		return dbus.Variant{}, err
+	}
+	return v, nil
+}
+
+func (b connBackend) GetAllProperties(ctx context.Context, path dbus.ObjectPath, iface string) (utils.DBusMapVariant, error) {
+	return utils.GetAllPropertiesContext(ctx, b.conn, iwdService, path, iface)
+}
+
+func (b connBackend) SetProperty(ctx context.Context, path dbus.ObjectPath, iface, name string,
+	value interface{}) error {
+
+	return utils.SetPropertyContext(ctx, b.conn, iwdService, path, iface, name, value)
+}
+
+func (b connBackend) GetManagedObjects(ctx context.Context) (utils.DBusRetValues, error) {
+	return utils.GetManagedObjectsContext(ctx, b.conn, iwdService)
+}
+
+func (b connBackend) AddSignalMatch(path dbus.ObjectPath, iface, member string) error {
+	return b.conn.AddMatchSignal(signalMatch(path, iface, member)...)
+}
+
+func (b connBackend) RemoveSignalMatch(path dbus.ObjectPath, iface, member string) error {
+	return b.conn.RemoveMatchSignal(signalMatch(path, iface, member)...)
+}
+
+func (b connBackend) Signal(ch chan<- *dbus.Signal) {
+	b.conn.Signal(ch)
+}
+
+func (b connBackend) WatchService(ch chan<- string) error {
+	match := []dbus.MatchOption{
+		dbus.WithMatchSender(dbusService),
+		dbus.WithMatchInterface(dbusService),
+		dbus.WithMatchMember(signalNameOwnerChanged),
+		dbus.WithMatchArg(0, iwdService),
+	}
+	if err := b.conn.AddMatchSignal(match...); err != nil {
+		return err
+	}
+	signals := make(chan *dbus.Signal, signalBuffer)
+	b.conn.Signal(signals)
+	var owner string
+	if err := b.conn.BusObject().Call(dbusService+".GetNameOwner", 0, iwdService).Store(&owner); err != nil {
+		var dbusErr dbus.Error
+		if !errors.As(err, &dbusErr) || dbusErr.Name != "org.freedesktop.DBus.Error.NameHasNoOwner" {
+			b.conn.RemoveSignal(signals)
+			b.conn.RemoveMatchSignal(match...)
+			return err
+		}
+	}
+	ch <- owner
+	go func() {
+		defer close(ch)
+		for sig := range signals {
+			if sig.Name != dbusService+"."+signalNameOwnerChanged || len(sig.Body) < 3 {
+				continue
+			}
+			if name, _ := sig.Body[0].(string); name != iwdService {
+				continue
+			}
+			owner, _ := sig.Body[2].(string)
+			ch <- owner
+		}
+	}()
+	return nil
+}
+
+func (b connBackend) Export(v interface{}, path dbus.ObjectPath, iface string) error {
+	return b.conn.Export(v, path, iface)
+}
+
+func (b connBackend) Close() error {
+	return b.conn.Close()
+}
+
+func signalMatch(path dbus.ObjectPath, iface, member string) []dbus.MatchOption {
+	match := []dbus.MatchOption{
+		dbus.WithMatchSender(iwdService),
+		dbus.WithMatchInterface(iface),
+		dbus.WithMatchMember(member),
+	}
+	if path != "" {
+		match = append(match, dbus.WithMatchObjectPath(path))
+	}
+	return match
+}
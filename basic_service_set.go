@@ -0,0 +1,41 @@
+package iwd
+
+import (
+	"context"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	iwdBasicServiceSetIface = iwdService + ".BasicServiceSet"
+)
+
+type BasicServiceSet struct {
+	Path    dbus.ObjectPath `iwd:"-"` // /net/connman/iwd/{phy0,phy1,...}/{1,2,...}/Xxx/{aabbccddeeff}
+	Address string          // [ro] The BSS's hardware address in the XX:XX:XX:XX:XX:XX format
+	object
+}
+
+func NewBasicServiceSet(p dbus.ObjectPath, i *Iwd) (*BasicServiceSet, error) {
+	return NewBasicServiceSetContext(context.Background(), p, i)
+}
+
+// NewBasicServiceSetContext is the context-aware variant of NewBasicServiceSet.
+func NewBasicServiceSetContext(ctx context.Context, p dbus.ObjectPath, i *Iwd) (*BasicServiceSet, error) {
+	return newBasicServiceSet(ctx, p, i, liveSource{i})
+}
+
+func newBasicServiceSet(ctx context.Context, p dbus.ObjectPath, i *Iwd, src propertySource) (*BasicServiceSet, error) {
+	objects, err := src.properties(ctx, p, iwdBasicServiceSetIface)
+	if err != nil {
+		return nil, err
+	}
+	bss := &BasicServiceSet{
+		Path:   p,
+		object: i.object(),
+	}
+	if err := decodeProperties(objects, p, iwdBasicServiceSetIface, bss); err != nil {
+		return nil, err
+	}
+	return bss, nil
+}
@@ -1,6 +1,8 @@
 package iwd
 
 import (
+	"context"
+
 	"github.com/godbus/dbus/v5"
 	"github.com/shtirlic/go-iwd/utils"
 )
@@ -12,7 +14,7 @@ const (
 
 type Daemon struct {
 	Path dbus.ObjectPath // /net/connman/iwd
-	iwd  *Iwd
+	object
 }
 
 type DaemonInfo struct {
@@ -22,12 +24,41 @@ type DaemonInfo struct {
 }
 
 func NewDaemon(p dbus.ObjectPath, i *Iwd) (*Daemon, error) {
+	return NewDaemonContext(context.Background(), p, i)
+}
+
+// NewDaemonContext is the context-aware variant of NewDaemon.
+func NewDaemonContext(ctx context.Context, p dbus.ObjectPath, i *Iwd) (*Daemon, error) {
 	return &Daemon{
-		Path: p,
-		iwd:  i,
+		Path:   p,
+		object: i.object(),
 	}, nil
 }
 
+// Check that the daemon is still available.  The daemon
+// object has no properties to reload; the returned error
+// matches ErrUnknownObject if it has disappeared, e.g.
+// because iwd was stopped.  Otherwise the daemon belongs
+// to the running iwd afterwards, see Valid; as that is
+// recorded in the daemon, it must not be used
+// concurrently.
+func (d *Daemon) Refresh() error {
+	return d.RefreshContext(context.Background())
+}
+
+// RefreshContext is the context-aware variant of Refresh.
+func (d *Daemon) RefreshContext(ctx context.Context) error {
+	if _, err := (liveSource{d.iwd}).properties(ctx, d.Path, iwdDaemonIface); err != nil {
+		return err
+	}
+	fresh, err := NewDaemonContext(ctx, d.Path, d.iwd)
+	if err != nil {
+		return err
+	}
+	*d = *fresh
+	return nil
+}
+
 // Returns basic IWD daemon's status and configuration
 // properties.  Their values are global and may be useful
 // for D-Bus clients interacting with IWD, not so much
@@ -35,7 +66,12 @@ func NewDaemon(p dbus.ObjectPath, i *Iwd) (*Daemon, error) {
 // string keys to values of types defined per key.
 // Clients should ignore unknown keys.
 func (d *Daemon) GetInfo() (*DaemonInfo, error) {
-	call, err := d.iwd.CallServiceMethod(d.Path, callDaemonGetIfno)
+	return d.GetInfoContext(context.Background())
+}
+
+// GetInfoContext is the context-aware variant of GetInfo.
+func (d *Daemon) GetInfoContext(ctx context.Context) (*DaemonInfo, error) {
+	call, err := d.callMethod(ctx, d.Path, callDaemonGetIfno)
 	if err != nil {
 		return nil, err
 	}
@@ -44,7 +80,7 @@ func (d *Daemon) GetInfo() (*DaemonInfo, error) {
 		return nil, err
 	}
 	var dinfo DaemonInfo
-	if err := utils.Transcode(objects, &dinfo); err != nil {
+	if err := utils.Decode(objects, &dinfo); err != nil {
 		return nil, err
 	}
 	return &dinfo, nil
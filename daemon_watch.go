@@ -0,0 +1,281 @@
+package iwd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	dbusService    = "org.freedesktop.DBus"
+	dbusDaemonPath = "/org/freedesktop/DBus"
+
+	signalNameOwnerChanged = "NameOwnerChanged"
+
+	// A restarted iwd may own its name before it serves
+	// all of its objects, so the calls made when it comes
+	// back are retried with growing delays.
+	restartAttempts = 5
+	restartBackoff  = 100 * time.Millisecond
+)
+
+// DaemonEvent reports iwd appearing on or vanishing from
+// the bus, e.g. when iwd.service is restarted.
+type DaemonEvent struct {
+	Running bool   // Whether iwd is running after the change
+	Owner   string // Unique bus name of the running iwd, empty if it vanished
+	Err     error  // Error registering the agents again with the running iwd, reported by an event of its own
+}
+
+// object ties an iwd object to the iwd instance it was
+// built from.  It is embedded in every object type.
+type object struct {
+	iwd *Iwd
+	gen uint64
+}
+
+func (i *Iwd) object() object {
+	return object{iwd: i, gen: i.gen.Load()}
+}
+
+// Valid reports whether the object belongs to the running
+// iwd.  Objects become invalid once iwd exits, since a
+// restarted iwd may reuse their paths for different
+// devices and networks; their methods then return
+// ErrStale.  Build new objects, e.g. with Refresh, after
+// iwd is back.
+func (o object) Valid() bool {
+	return o.gen == o.iwd.gen.Load()
+}
+
+// callMethod calls method on the iwd object at path, or
+// returns ErrStale if the object is no longer valid.
+func (o object) callMethod(ctx context.Context, path dbus.ObjectPath, method string,
+	args ...interface{}) (*dbus.Call, error) {
+
+	if !o.Valid() {
+		return nil, fmt.Errorf("%w: %s", ErrStale, path)
+	}
+	return o.iwd.CallServiceMethodContext(ctx, path, method, args...)
+}
+
+// setProperty sets a property of the iwd object at path,
+// or returns ErrStale if the object is no longer valid.
+func (o object) setProperty(ctx context.Context, path dbus.ObjectPath, iface, name string,
+	value interface{}) error {
+
+	if !o.Valid() {
+		return fmt.Errorf("%w: %s", ErrStale, path)
+	}
+	return o.iwd.SetServicePropertyContext(ctx, path, iface, name, value)
+}
+
+// Subscribe to iwd appearing on and vanishing from the bus.
+// While iwd restarts, the Iwd keeps all subscriptions and,
+// once iwd is back, registers the agents registered with
+// RegisterAgent and the signal level agents again in the
+// background; if that fails, another event with Running
+// set reports the error.  Managers reload their tree.
+// Objects built before iwd vanished become invalid, see
+// Valid.
+func (i *Iwd) SubscribeDaemonEvents(ctx context.Context) (*Subscription[DaemonEvent], error) {
+	return subscribe(ctx, i, "", dbusService, signalNameOwnerChanged, decodeDaemonEvent)
+}
+
+func decodeDaemonEvent(sig *dbus.Signal) (DaemonEvent, bool) {
+	if len(sig.Body) < 3 {
+		return DaemonEvent{}, false
+	}
+	owner, _ := sig.Body[2].(string)
+	event := DaemonEvent{Running: owner != "", Owner: owner}
+	if len(sig.Body) > 3 {
+		event.Err, _ = sig.Body[3].(error)
+	}
+	return event, true
+}
+
+// watchDaemon follows the owner of the iwd service name
+// for the lifetime of the backend.
+func (i *Iwd) watchDaemon() {
+	owners := make(chan string, signalBuffer)
+	if err := i.backend.WatchService(owners); err != nil {
+		// Without a watch the Iwd works as before, it only
+		// does not notice restarts.
+		return
+	}
+	// WatchService sends the current owner before it
+	// returns; a backend that does not is not waited for,
+	// the first owner it sends is taken as the current one.
+	select {
+	case owner, ok := <-owners:
+		if !ok {
+			return
+		}
+		i.setInitialOwner(owner)
+		go i.followOwners(owners)
+	default:
+		go func() {
+			owner, ok := <-owners
+			if !ok {
+				return
+			}
+			i.setInitialOwner(owner)
+			i.followOwners(owners)
+		}()
+	}
+}
+
+func (i *Iwd) setInitialOwner(owner string) {
+	i.ownerMu.Lock()
+	defer i.ownerMu.Unlock()
+	i.owner = owner
+	i.watching = true
+}
+
+func (i *Iwd) followOwners(owners <-chan string) {
+	for owner := range owners {
+		i.daemonChanged(owner)
+	}
+}
+
+func (i *Iwd) daemonChanged(owner string) {
+	i.daemonMu.Lock()
+	defer i.daemonMu.Unlock()
+	i.ownerMu.Lock()
+	old := i.owner
+	i.owner = owner
+	// Until the held signals are replayed, the new
+	// owner's are held too.
+	i.replaying = true
+	i.ownerMu.Unlock()
+	if old == owner {
+		i.replayHeld()
+		return
+	}
+	if old != "" {
+		i.gen.Add(1)
+	}
+	i.dispatchDaemonEvent(old, owner, nil)
+	i.replayHeld()
+	if owner != "" {
+		go i.reregister(i.gen.Load(), owner)
+	}
+}
+
+// dispatchDaemonEvent hands a change of the iwd owner to
+// the handlers as the NameOwnerChanged signal it stands
+// for; err follows its arguments for decodeDaemonEvent.
+func (i *Iwd) dispatchDaemonEvent(old, owner string, err error) {
+	i.dispatchSignal(&dbus.Signal{
+		Sender: dbusService,
+		Path:   dbusDaemonPath,
+		Name:   dbusService + "." + signalNameOwnerChanged,
+		Body:   []interface{}{iwdService, old, owner, err},
+	}, true)
+}
+
+// reregister registers the agents and signal level agents
+// again with the iwd of generation gen, owned by owner.  A
+// failure is reported with another DaemonEvent, unless
+// that iwd is gone by then.
+func (i *Iwd) reregister(gen uint64, owner string) {
+	err := errors.Join(i.reregisterAgents(gen), i.reregisterSignalLevelAgents(gen))
+	if err == nil {
+		return
+	}
+	i.daemonMu.Lock()
+	defer i.daemonMu.Unlock()
+	if i.gen.Load() != gen {
+		return
+	}
+	i.dispatchDaemonEvent(owner, owner, err)
+}
+
+// reregisterAgents registers the agents again with the iwd
+// of generation gen.  An agent that cannot be registered
+// stays exported and is tried again on the next restart.
+func (i *Iwd) reregisterAgents(gen uint64) error {
+	i.agentMu.Lock()
+	paths := make([]dbus.ObjectPath, 0, len(i.agents))
+	for p := range i.agents {
+		paths = append(paths, p)
+	}
+	i.agentMu.Unlock()
+	var errs []error
+	for _, p := range paths {
+		err := i.retry(gen, func() error {
+			i.agentMu.Lock()
+			_, ok := i.agents[p]
+			i.agentMu.Unlock()
+			if !ok {
+				// Unregistered meanwhile.
+				return nil
+			}
+			_, err := i.CallServiceMethod(iwdObjPath, callAgentManagerRegisterAgent, p)
+			return err
+		})
+		if errors.Is(err, errVanished) {
+			// The next restart registers it again.
+			return nil
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("register agent %s: %w", p, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// errVanished is returned by retry once the iwd it was
+// retrying against is gone.
+var errVanished = fmt.Errorf("%w: iwd vanished", ErrStale)
+
+// retry calls fn until it succeeds, at most restartAttempts
+// times, and returns its last error.  It gives up with
+// errVanished once the iwd of generation gen is gone.
+func (i *Iwd) retry(gen uint64, fn func() error) error {
+	delay := restartBackoff
+	var err error
+	for n := 0; n < restartAttempts; n++ {
+		if n > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+		if i.gen.Load() != gen {
+			return errVanished
+		}
+		if err = fn(); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// reregisterSignalLevelAgents registers the signal level
+// agents again with the stations at the same paths of the
+// iwd of generation gen.  An agent that cannot be
+// registered stays open and is tried again on the next
+// restart.
+func (i *Iwd) reregisterSignalLevelAgents(gen uint64) error {
+	i.agentMu.Lock()
+	agents := make([]*SignalLevelAgent, 0, len(i.levelAgents))
+	for a := range i.levelAgents {
+		agents = append(agents, a)
+	}
+	i.agentMu.Unlock()
+	var errs []error
+	for _, a := range agents {
+		err := i.retry(gen, func() error {
+			return a.register(context.Background(), gen)
+		})
+		if errors.Is(err, errVanished) {
+			return nil
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("register signal level agent %s: %w", a.Path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
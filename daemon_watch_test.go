@@ -0,0 +1,32 @@
+package iwd
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRetryVanished(t *testing.T) {
+	i := &Iwd{}
+	gen := i.gen.Load()
+	i.gen.Add(1)
+	called := false
+	err := i.retry(gen, func() error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, ErrStale) || called {
+		t.Errorf("retry() for a vanished iwd = %v, called %v; want ErrStale without a call", err, called)
+	}
+
+	calls := 0
+	err = i.retry(i.gen.Load(), func() error {
+		calls++
+		if calls == 2 {
+			i.gen.Add(1)
+		}
+		return errors.New("not ready")
+	})
+	if !errors.Is(err, ErrStale) || calls != 2 {
+		t.Errorf("retry() while iwd vanishes = %v after %d calls, want ErrStale after 2", err, calls)
+	}
+}
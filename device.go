@@ -1,8 +1,9 @@
 package iwd
 
 import (
+	"context"
+
 	"github.com/godbus/dbus/v5"
-	"github.com/shtirlic/go-iwd/utils"
 )
 
 const (
@@ -10,33 +11,83 @@ const (
 )
 
 type Device struct {
-	Path    dbus.ObjectPath // /net/connman/iwd/{phy0,phy1,...}/{1,2,...}
-	Adapter *Adapter        // [ro] Adapter the device belongs to
+	Path    dbus.ObjectPath `iwd:"-"` // /net/connman/iwd/{phy0,phy1,...}/{1,2,...}
+	Adapter *Ref[Adapter]   `iwd:"-"` // [ro] Adapter the device belongs to
 	Address string          // [ro] Interface's hardware address in the XX:XX:XX:XX:XX:XX format
 	Mode    DeviceMode      // [rw] Use to set the device mode
 	Name    string          // [ro] Device's interface name
 	Powered bool            // [rw]
-	iwd     *Iwd
+	object
 }
 
 func NewDevice(p dbus.ObjectPath, i *Iwd) (*Device, error) {
-	objects, err := utils.GetAllProperties(i.conn, iwdService, p, iwdDeviceIface)
+	return NewDeviceContext(context.Background(), p, i)
+}
+
+// NewDeviceContext is the context-aware variant of NewDevice.
+func NewDeviceContext(ctx context.Context, p dbus.ObjectPath, i *Iwd) (*Device, error) {
+	return newDevice(ctx, p, i, liveSource{i})
+}
+
+func newDevice(ctx context.Context, p dbus.ObjectPath, i *Iwd, src propertySource) (*Device, error) {
+	objects, err := src.properties(ctx, p, iwdDeviceIface)
 	if err != nil {
 		return nil, err
 	}
-	var adapter *Adapter
-	if adapterValue := objects["Adapter"].Value(); adapterValue != nil {
-		if adapter, err = NewAdapter(adapterValue.(dbus.ObjectPath), i); err != nil {
-			return nil, err
-		}
-	}
-	return &Device{
+	device := &Device{
 		Path:    p,
-		Adapter: adapter,
-		Address: objects["Address"].Value().(string),
-		Mode:    DeviceMode(objects["Mode"].Value().(string)),
-		Name:    objects["Name"].Value().(string),
-		Powered: objects["Powered"].Value().(bool),
-		iwd:     i,
-	}, nil
+		Adapter: optionalRef(objects["Adapter"], iwdAdapterIface, i, src, newAdapter),
+		object:  i.object(),
+	}
+	if err := decodeProperties(objects, p, iwdDeviceIface, device); err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+// Reload the properties of the device from iwd in place.
+// The returned error matches ErrUnknownObject if the
+// device has disappeared since.  Refresh writes the
+// fields, so the device must not be used concurrently.
+func (d *Device) Refresh() error {
+	return d.RefreshContext(context.Background())
+}
+
+// RefreshContext is the context-aware variant of Refresh.
+func (d *Device) RefreshContext(ctx context.Context) error {
+	fresh, err := NewDeviceContext(ctx, d.Path, d.iwd)
+	if err != nil {
+		return err
+	}
+	*d = *fresh
+	return nil
+}
+
+// Power the device on or off.
+func (d *Device) SetPowered(powered bool) error {
+	return d.SetPoweredContext(context.Background(), powered)
+}
+
+// SetPoweredContext is the context-aware variant of SetPowered.
+func (d *Device) SetPoweredContext(ctx context.Context, powered bool) error {
+	if err := d.setProperty(ctx, d.Path, iwdDeviceIface, "Powered", powered); err != nil {
+		return err
+	}
+	d.Powered = powered
+	return nil
+}
+
+// Switch the device to mode.  The mode must be one of
+// the adapter's SupportedModes.
+func (d *Device) SetMode(mode DeviceMode) error {
+	return d.SetModeContext(context.Background(), mode)
+}
+
+// SetModeContext is the context-aware variant of SetMode.
+func (d *Device) SetModeContext(ctx context.Context, mode DeviceMode) error {
+	if err := d.setProperty(ctx, d.Path, iwdDeviceIface, "Mode", string(mode)); err != nil {
+		return err
+	}
+	d.Mode = mode
+	return nil
 }
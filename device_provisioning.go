@@ -0,0 +1,137 @@
+package iwd
+
+import (
+	"context"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	iwdDeviceProvisioningIface = iwdService + ".DeviceProvisioning"
+
+	callDeviceProvisioningStartEnrollee     = iwdDeviceProvisioningIface + ".StartEnrollee"
+	callDeviceProvisioningStartConfigurator = iwdDeviceProvisioningIface + ".StartConfigurator"
+	callDeviceProvisioningConfigureEnrollee = iwdDeviceProvisioningIface + ".ConfigureEnrollee"
+	callDeviceProvisioningStop              = iwdDeviceProvisioningIface + ".Stop"
+)
+
+// Indicates the DPP role of the device.  One of:
+// "enrollee", "configurator"
+type ProvisioningRole string
+
+const (
+	EnrolleeProvisioningRole     ProvisioningRole = "enrollee"
+	ConfiguratorProvisioningRole ProvisioningRole = "configurator"
+)
+
+type DeviceProvisioning struct {
+	Path    dbus.ObjectPath  `iwd:"-"` // /net/connman/iwd/{phy0,phy1,...}/{1,2,...}
+	Started bool             // [ro] True if DPP is currently active.
+	Role    ProvisioningRole // [ro] Indicates the DPP role, if started
+	URI     string           // [ro] The DPP URI used by the device, if started
+	object
+}
+
+func NewDeviceProvisioning(p dbus.ObjectPath, i *Iwd) (*DeviceProvisioning, error) {
+	return NewDeviceProvisioningContext(context.Background(), p, i)
+}
+
+// NewDeviceProvisioningContext is the context-aware variant of NewDeviceProvisioning.
+func NewDeviceProvisioningContext(ctx context.Context, p dbus.ObjectPath, i *Iwd) (*DeviceProvisioning, error) {
+	return newDeviceProvisioning(ctx, p, i, liveSource{i})
+}
+
+func newDeviceProvisioning(ctx context.Context, p dbus.ObjectPath, i *Iwd, src propertySource) (*DeviceProvisioning, error) {
+	objects, err := src.properties(ctx, p, iwdDeviceProvisioningIface)
+	if err != nil {
+		return nil, err
+	}
+	dpp := &DeviceProvisioning{
+		Path:   p,
+		object: i.object(),
+	}
+	if err := decodeProperties(objects, p, iwdDeviceProvisioningIface, dpp); err != nil {
+		return nil, err
+	}
+	return dpp, nil
+}
+
+// Return the DeviceProvisioning interface of the station.
+func (s *Station) DeviceProvisioning() (*DeviceProvisioning, error) {
+	return s.DeviceProvisioningContext(context.Background())
+}
+
+// DeviceProvisioningContext is the context-aware variant of DeviceProvisioning.
+func (s *Station) DeviceProvisioningContext(ctx context.Context) (*DeviceProvisioning, error) {
+	return NewDeviceProvisioningContext(ctx, s.Path, s.iwd)
+}
+
+// Start a DPP enrollee.  The returned URI should be
+// displayed (usually as a QR code) so that a
+// configurator can scan it and provision this device.
+func (d *DeviceProvisioning) StartEnrollee() (string, error) {
+	return d.StartEnrolleeContext(context.Background())
+}
+
+// StartEnrolleeContext is the context-aware variant of StartEnrollee.
+func (d *DeviceProvisioning) StartEnrolleeContext(ctx context.Context) (string, error) {
+	call, err := d.callMethod(ctx, d.Path, callDeviceProvisioningStartEnrollee)
+	if err != nil {
+		return "", err
+	}
+	var uri string
+	if err := call.Store(&uri); err != nil {
+		return "", err
+	}
+	return uri, nil
+}
+
+// Start a DPP configurator.  The device must be
+// connected to a network, which is the network the
+// enrollee will be configured for.  The returned URI
+// should be scanned by the enrollee.
+func (d *DeviceProvisioning) StartConfigurator() (string, error) {
+	return d.StartConfiguratorContext(context.Background())
+}
+
+// StartConfiguratorContext is the context-aware variant of StartConfigurator.
+func (d *DeviceProvisioning) StartConfiguratorContext(ctx context.Context) (string, error) {
+	call, err := d.callMethod(ctx, d.Path, callDeviceProvisioningStartConfigurator)
+	if err != nil {
+		return "", err
+	}
+	var uri string
+	if err := call.Store(&uri); err != nil {
+		return "", err
+	}
+	return uri, nil
+}
+
+// Start a DPP configurator and configure the enrollee
+// identified by uri (usually obtained by scanning its
+// QR code) with the currently connected network.
+func (d *DeviceProvisioning) ConfigureEnrollee(uri string) error {
+	return d.ConfigureEnrolleeContext(context.Background(), uri)
+}
+
+// ConfigureEnrolleeContext is the context-aware variant of ConfigureEnrollee.
+func (d *DeviceProvisioning) ConfigureEnrolleeContext(ctx context.Context, uri string) error {
+	if _, err := d.callMethod(ctx, d.Path, callDeviceProvisioningConfigureEnrollee, uri); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Stop an enrollee or configurator that is currently
+// running.
+func (d *DeviceProvisioning) Stop() error {
+	return d.StopContext(context.Background())
+}
+
+// StopContext is the context-aware variant of Stop.
+func (d *DeviceProvisioning) StopContext(ctx context.Context) error {
+	if _, err := d.callMethod(ctx, d.Path, callDeviceProvisioningStop); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package iwd
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Error is an error returned by iwd in the net.connman.iwd
+// error namespace.  Use errors.Is with the Err* values to
+// check for a specific error, or errors.As to access the
+// D-Bus name and message.
+type Error struct {
+	Name    string // D-Bus error name, e.g. net.connman.iwd.NotFound
+	Message string // Human readable message sent by iwd, if any
+	err     error
+}
+
+var (
+	ErrBusy               = &Error{Name: iwdService + ".Busy"}
+	ErrFailed             = &Error{Name: iwdService + ".Failed"}
+	ErrInvalidArgs        = &Error{Name: iwdService + ".InvalidArguments"}
+	ErrInvalidFormat      = &Error{Name: iwdService + ".InvalidFormat"}
+	ErrAlreadyExists      = &Error{Name: iwdService + ".AlreadyExists"}
+	ErrNotFound           = &Error{Name: iwdService + ".NotFound"}
+	ErrNotSupported       = &Error{Name: iwdService + ".NotSupported"}
+	ErrNoAgent            = &Error{Name: iwdService + ".NoAgent"}
+	ErrNotConnected       = &Error{Name: iwdService + ".NotConnected"}
+	ErrNotConfigured      = &Error{Name: iwdService + ".NotConfigured"}
+	ErrNotImplemented     = &Error{Name: iwdService + ".NotImplemented"}
+	ErrServiceSetOverlap  = &Error{Name: iwdService + ".ServiceSetOverlap"}
+	ErrAlreadyProvisioned = &Error{Name: iwdService + ".AlreadyProvisioned"}
+	ErrNotHidden          = &Error{Name: iwdService + ".NotHidden"}
+	ErrAborted            = &Error{Name: iwdService + ".Aborted"}
+	ErrNotAvailable       = &Error{Name: iwdService + ".NotAvailable"}
+	ErrPermissionDenied   = &Error{Name: iwdService + ".PermissionDenied"}
+	ErrTimeout            = &Error{Name: iwdService + ".Timeout"}
+	ErrInProgress         = &Error{Name: iwdService + ".InProgress"}
+	ErrAgentCanceled      = &Error{Name: agentErrorCanceled}
+)
+
+// ErrStale is returned by the methods of an object built
+// before iwd restarted, see Valid.
+var ErrStale = errors.New("iwd: stale object")
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return e.Name
+	}
+	return e.Name + ": " + e.Message
+}
+
+// Is reports whether target is an *Error with the same
+// D-Bus name, so that errors.Is(err, ErrNotFound) matches
+// any NotFound error regardless of its message.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Name == e.Name
+}
+
+// Unwrap returns the underlying dbus.Error.
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// wrapError converts D-Bus errors in the net.connman.iwd
+// namespace to *Error and returns any other error as is.
+func wrapError(err error) error {
+	var dbusErr dbus.Error
+	if !errors.As(err, &dbusErr) || !strings.HasPrefix(dbusErr.Name, iwdService+".") {
+		return err
+	}
+	var message string
+	if len(dbusErr.Body) > 0 {
+		message, _ = dbusErr.Body[0].(string)
+	}
+	return &Error{
+		Name:    dbusErr.Name,
+		Message: message,
+		err:     err,
+	}
+}
@@ -1,6 +1,11 @@
 package iwd
 
 import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+
 	"github.com/godbus/dbus/v5"
 	"github.com/shtirlic/go-iwd/utils"
 )
@@ -8,10 +13,30 @@ import (
 const (
 	iwdService = "net.connman.iwd"
 	iwdObjPath = "/net/connman/iwd"
+
+	callGetManagedObjects = dbusObjectManagerIface + ".GetManagedObjects"
+	callPropertiesGet     = dbusPropertiesIface + ".Get"
+	callPropertiesGetAll  = dbusPropertiesIface + ".GetAll"
+	callPropertiesSet     = dbusPropertiesIface + ".Set"
 )
 
 type Iwd struct {
-	conn *dbus.Conn
+	backend     Backend
+	sigMu       sync.Mutex
+	sigHandlers map[*signalHandler]struct{}
+	recMu       sync.Mutex
+	rec         *json.Encoder
+	recErr      error // First error of rec, see RecordErr
+	ownerMu     sync.Mutex
+	owner       string         // Unique bus name of iwd, empty while it is not running
+	watching    bool           // Whether owner follows the service
+	held        []*dbus.Signal // Signals of another sender than owner, see admit
+	replaying   bool           // Whether held signals are being dispatched
+	gen         atomic.Uint64  // Incremented every time iwd vanishes
+	daemonMu    sync.Mutex     // Orders the DaemonEvents
+	agentMu     sync.Mutex
+	agents      map[dbus.ObjectPath]struct{}
+	levelAgents map[*SignalLevelAgent]struct{}
 }
 
 func NewIwd() (*Iwd, error) {
@@ -23,39 +48,145 @@ func NewIwd() (*Iwd, error) {
 }
 
 func NewIwdWithConn(conn *dbus.Conn) *Iwd {
-	return &Iwd{conn: conn}
+	return NewIwdWithBackend(NewConnBackend(conn))
+}
+
+// NewIwdWithBackend returns an Iwd that reaches iwd through
+// b instead of a D-Bus connection of its own.  It asks b to
+// watch the iwd service, see Backend.WatchService, which
+// for the godbus backend takes a round trip to the bus.
+func NewIwdWithBackend(b Backend) *Iwd {
+	i := &Iwd{
+		backend:     b,
+		agents:      make(map[dbus.ObjectPath]struct{}),
+		levelAgents: make(map[*SignalLevelAgent]struct{}),
+	}
+	i.watchDaemon()
+	return i
 }
 
 func (i *Iwd) Close() error {
-	return i.conn.Close()
+	return i.backend.Close()
 }
 
 func (i *Iwd) Stations() ([]*Station, error) {
-	return utils.GetObjectsByInterface(i.conn, iwdService, iwdStationIface, NewStation, i)
+	return i.StationsContext(context.Background())
+}
+
+func (i *Iwd) StationsContext(ctx context.Context) ([]*Station, error) {
+	return listObjects(ctx, i, iwdStationIface, newStation)
 }
 
 func (i *Iwd) Networks() ([]*Network, error) {
-	return utils.GetObjectsByInterface(i.conn, iwdService, iwdNetworkIface, NewNetwork, i)
+	return i.NetworksContext(context.Background())
+}
+
+func (i *Iwd) NetworksContext(ctx context.Context) ([]*Network, error) {
+	return listObjects(ctx, i, iwdNetworkIface, newNetwork)
+}
+
+func (i *Iwd) BasicServiceSets() ([]*BasicServiceSet, error) {
+	return i.BasicServiceSetsContext(context.Background())
+}
+
+func (i *Iwd) BasicServiceSetsContext(ctx context.Context) ([]*BasicServiceSet, error) {
+	return listObjects(ctx, i, iwdBasicServiceSetIface, newBasicServiceSet)
 }
 
 func (i *Iwd) Adapters() ([]*Adapter, error) {
-	return utils.GetObjectsByInterface(i.conn, iwdService, iwdAdapterIface, NewAdapter, i)
+	return i.AdaptersContext(context.Background())
+}
+
+func (i *Iwd) AdaptersContext(ctx context.Context) ([]*Adapter, error) {
+	return listObjects(ctx, i, iwdAdapterIface, newAdapter)
 }
 
 func (i *Iwd) Devices() ([]*Device, error) {
-	return utils.GetObjectsByInterface(i.conn, iwdService, iwdDeviceIface, NewDevice, i)
+	return i.DevicesContext(context.Background())
+}
+
+func (i *Iwd) DevicesContext(ctx context.Context) ([]*Device, error) {
+	return listObjects(ctx, i, iwdDeviceIface, newDevice)
 }
 
 func (i *Iwd) KnownNetworks() ([]*KnownNetwork, error) {
-	return utils.GetObjectsByInterface(i.conn, iwdService, iwdKnownNetworkIface, NewKnownNetwork, i)
+	return i.KnownNetworksContext(context.Background())
+}
+
+func (i *Iwd) KnownNetworksContext(ctx context.Context) ([]*KnownNetwork, error) {
+	return listObjects(ctx, i, iwdKnownNetworkIface, newKnownNetwork)
+}
+
+func (i *Iwd) AccessPoints() ([]*AccessPoint, error) {
+	return i.AccessPointsContext(context.Background())
+}
+
+func (i *Iwd) AccessPointsContext(ctx context.Context) ([]*AccessPoint, error) {
+	return listObjects(ctx, i, iwdAccessPointIface, newAccessPoint)
+}
+
+func (i *Iwd) AdHocs() ([]*AdHoc, error) {
+	return i.AdHocsContext(context.Background())
+}
+
+func (i *Iwd) AdHocsContext(ctx context.Context) ([]*AdHoc, error) {
+	return listObjects(ctx, i, iwdAdHocIface, newAdHoc)
+}
+
+func (i *Iwd) DeviceProvisionings() ([]*DeviceProvisioning, error) {
+	return i.DeviceProvisioningsContext(context.Background())
+}
+
+func (i *Iwd) DeviceProvisioningsContext(ctx context.Context) ([]*DeviceProvisioning, error) {
+	return listObjects(ctx, i, iwdDeviceProvisioningIface, newDeviceProvisioning)
+}
+
+func (i *Iwd) SharedCodeDeviceProvisionings() ([]*SharedCodeDeviceProvisioning, error) {
+	return i.SharedCodeDeviceProvisioningsContext(context.Background())
+}
+
+func (i *Iwd) SharedCodeDeviceProvisioningsContext(ctx context.Context) ([]*SharedCodeDeviceProvisioning, error) {
+	return listObjects(ctx, i, iwdSharedCodeDeviceProvisioningIface, newSharedCodeDeviceProvisioning)
+}
+
+func (i *Iwd) P2PDevices() ([]*P2PDevice, error) {
+	return i.P2PDevicesContext(context.Background())
+}
+
+func (i *Iwd) P2PDevicesContext(ctx context.Context) ([]*P2PDevice, error) {
+	return listObjects(ctx, i, iwdP2PDeviceIface, newP2PDevice)
+}
+
+func (i *Iwd) Peers() ([]*Peer, error) {
+	return i.PeersContext(context.Background())
 }
 
-func (i *Iwd) daemons() ([]*Daemon, error) {
-	return utils.GetObjectsByInterface(i.conn, iwdService, iwdDaemonIface, NewDaemon, i)
+func (i *Iwd) PeersContext(ctx context.Context) ([]*Peer, error) {
+	return listObjects(ctx, i, iwdP2PPeerIface, newPeer)
+}
+
+func (i *Iwd) daemons(ctx context.Context) ([]*Daemon, error) {
+	g, err := loadGraph(ctx, i)
+	if err != nil {
+		return nil, err
+	}
+	var daemons []*Daemon
+	for _, p := range g.paths(iwdDaemonIface) {
+		if d, err := NewDaemonContext(ctx, p, i); err == nil {
+			daemons = append(daemons, d)
+		} else {
+			return nil, err
+		}
+	}
+	return daemons, nil
 }
 
 func (i *Iwd) Daemon() (*Daemon, error) {
-	if d, err := i.daemons(); err == nil {
+	return i.DaemonContext(context.Background())
+}
+
+func (i *Iwd) DaemonContext(ctx context.Context) (*Daemon, error) {
+	if d, err := i.daemons(ctx); err == nil {
 		return d[0], nil
 	} else {
 		return nil, err
@@ -63,7 +194,11 @@ func (i *Iwd) Daemon() (*Daemon, error) {
 }
 
 func (i *Iwd) WSC() ([]*WSC, error) {
-	stations, err := i.Stations()
+	return i.WSCContext(context.Background())
+}
+
+func (i *Iwd) WSCContext(ctx context.Context) ([]*WSC, error) {
+	stations, err := i.StationsContext(ctx)
 	var wscs []*WSC
 	if err != nil {
 		return nil, err
@@ -78,6 +213,83 @@ func (i *Iwd) WSC() ([]*WSC, error) {
 	return wscs, nil
 }
 
+// listObjects builds every object implementing iface from a
+// single GetManagedObjects call.
+func listObjects[T any](ctx context.Context, i *Iwd, iface string,
+	constructor func(context.Context, dbus.ObjectPath, *Iwd, propertySource) (*T, error)) ([]*T, error) {
+
+	g, err := loadGraph(ctx, i)
+	if err != nil {
+		return nil, err
+	}
+	return buildAll(ctx, g, iface, i, constructor)
+}
+
+// CallServiceMethod calls method on the iwd object at path.
+// Errors in the net.connman.iwd namespace are returned as
+// *Error.
 func (i *Iwd) CallServiceMethod(path dbus.ObjectPath, method string, args ...interface{}) (*dbus.Call, error) {
-	return utils.CallMethod(i.conn, iwdService, path, method, args...)
+	return i.CallServiceMethodContext(context.Background(), path, method, args...)
+}
+
+// CallServiceMethodContext is like CallServiceMethod but
+// gives up waiting for the reply once ctx is done.
+func (i *Iwd) CallServiceMethodContext(ctx context.Context, path dbus.ObjectPath, method string,
+	args ...interface{}) (*dbus.Call, error) {
+
+	return i.call(ctx, path, method, args...)
+}
+
+// SetServiceProperty sets the property name of iface on the
+// iwd object at path.  Errors in the net.connman.iwd
+// namespace are returned as *Error.
+func (i *Iwd) SetServiceProperty(path dbus.ObjectPath, iface string, name string, value interface{}) error {
+	return i.SetServicePropertyContext(context.Background(), path, iface, name, value)
+}
+
+// SetServicePropertyContext is like SetServiceProperty but
+// gives up waiting for the reply once ctx is done.
+func (i *Iwd) SetServicePropertyContext(ctx context.Context, path dbus.ObjectPath, iface string, name string,
+	value interface{}) error {
+
+	err := i.backend.SetProperty(ctx, path, iface, name, value)
+	i.recordCall(path, callPropertiesSet, []interface{}{iface, name, dbus.MakeVariant(value)}, nil, err)
+	return wrapError(err)
+}
+
+// The methods below are the only way to reach the backend,
+// so that recordings made with Record are complete.  Calls
+// are recorded as the D-Bus method calls they stand for,
+// with the error iwd replied; callers get it converted by
+// wrapError.
+
+func (i *Iwd) call(ctx context.Context, path dbus.ObjectPath, method string, args ...interface{}) (*dbus.Call, error) {
+	call, err := i.backend.CallMethod(ctx, path, method, args...)
+	var reply []interface{}
+	if err == nil {
+		reply = call.Body
+	}
+	i.recordCall(path, method, args, reply, err)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	return call, nil
+}
+
+func (i *Iwd) getManagedObjects(ctx context.Context) (utils.DBusRetValues, error) {
+	objects, err := i.backend.GetManagedObjects(ctx)
+	i.recordCall("/", callGetManagedObjects, nil, []interface{}{objects}, err)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	return objects, nil
+}
+
+func (i *Iwd) getAllProperties(ctx context.Context, path dbus.ObjectPath, iface string) (utils.DBusMapVariant, error) {
+	objects, err := i.backend.GetAllProperties(ctx, path, iface)
+	i.recordCall(path, callPropertiesGetAll, []interface{}{iface}, []interface{}{objects}, err)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	return objects, nil
 }
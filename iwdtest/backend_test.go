@@ -0,0 +1,433 @@
+package iwdtest_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	iwd "github.com/shtirlic/go-iwd"
+	"github.com/shtirlic/go-iwd/iwdtest"
+)
+
+// testAgent answers passphrase requests with "secret" and
+// counts them.
+type testAgent struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (a *testAgent) Release() {}
+
+func (a *testAgent) RequestPassphrase(n *iwd.Network) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.requests = append(a.requests, n.Name)
+	return "secret", nil
+}
+
+func (a *testAgent) RequestPrivateKeyPassphrase(n *iwd.Network) (string, error) {
+	return "", errors.New("not supported")
+}
+
+func (a *testAgent) RequestUserNameAndPassword(n *iwd.Network) (string, string, error) {
+	return "", "", errors.New("not supported")
+}
+
+func (a *testAgent) RequestUserPassword(n *iwd.Network, user string) (string, error) {
+	return "", errors.New("not supported")
+}
+
+func (a *testAgent) Cancel(reason iwd.AgentCancelReason) {}
+
+func (a *testAgent) Requests() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.requests...)
+}
+
+// forEachServer runs test against a memory server and, if
+// dbus-daemon is installed, a server on a private bus.
+func forEachServer(t *testing.T, test func(t *testing.T, srv *iwdtest.Server, i *iwd.Iwd)) {
+	t.Run("memory", func(t *testing.T) {
+		srv := iwdtest.NewMemoryServer()
+		defer srv.Close()
+		i := iwd.NewIwdWithBackend(srv.Backend())
+		defer i.Close()
+		test(t, srv, i)
+	})
+	t.Run("bus", func(t *testing.T) {
+		if _, err := exec.LookPath("dbus-daemon"); err != nil {
+			t.Skip("dbus-daemon not installed")
+		}
+		srv, err := iwdtest.NewServer()
+		if err != nil {
+			t.Fatalf("NewServer() = %v", err)
+		}
+		defer srv.Close()
+		conn, err := srv.Conn()
+		if err != nil {
+			t.Fatalf("Conn() = %v", err)
+		}
+		i := iwd.NewIwdWithConn(conn)
+		defer i.Close()
+		test(t, srv, i)
+	})
+}
+
+// receive returns the next value of ch, failing the test
+// if none arrives in time.
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %T", *new(T))
+		panic("unreachable")
+	}
+}
+
+// eventually fails the test unless cond becomes true in
+// time.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBackendObjects(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *iwdtest.Server, i *iwd.Iwd) {
+		adapter := srv.AddAdapter("phy0")
+		station := srv.AddStation(adapter, "wlan0", "02:00:00:00:00:01")
+		network := srv.AddNetwork(station, "Cafe", "psk", -6000)
+		srv.AddBasicServiceSet(network, "02:00:00:00:01:01")
+
+		stations, err := i.Stations()
+		if err != nil {
+			t.Fatalf("Stations() = %v", err)
+		}
+		if len(stations) != 1 || stations[0].Path != station || stations[0].State != iwd.DisconnectedState {
+			t.Fatalf("Stations() = %+v, want the disconnected station %s", stations, station)
+		}
+		networks, err := stations[0].GetOrderedNetworks()
+		if err != nil {
+			t.Fatalf("GetOrderedNetworks() = %v", err)
+		}
+		if len(networks) != 1 || networks[0].Name != "Cafe" || networks[0].Type != "psk" {
+			t.Fatalf("GetOrderedNetworks() = %+v, want Cafe", networks)
+		}
+		device, err := networks[0].Device.Resolve()
+		if err != nil {
+			t.Fatalf("Device.Resolve() = %v", err)
+		}
+		if device.Name != "wlan0" || device.Address != "02:00:00:00:00:01" {
+			t.Errorf("Device.Resolve() = %+v, want wlan0", device)
+		}
+		bss, err := networks[0].ExtendedServiceSet[0].Resolve()
+		if err != nil {
+			t.Fatalf("ExtendedServiceSet[0].Resolve() = %v", err)
+		}
+		if bss.Address != "02:00:00:00:01:01" {
+			t.Errorf("BasicServiceSet.Address = %q", bss.Address)
+		}
+
+		if _, err := iwd.NewStation("/net/connman/iwd/nope", i); !errors.Is(err, iwd.ErrUnknownObject) {
+			t.Errorf("NewStation(unknown) = %v, want ErrUnknownObject", err)
+		}
+	})
+}
+
+func TestBackendErrors(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *iwdtest.Server, i *iwd.Iwd) {
+		station := srv.AddStation(srv.AddAdapter("phy0"), "wlan0", "02:00:00:00:00:01")
+		srv.AddNetwork(station, "Cafe", "psk", -6000)
+		networks, err := i.Networks()
+		if err != nil {
+			t.Fatalf("Networks() = %v", err)
+		}
+		err = networks[0].Connect()
+		if !errors.Is(err, iwd.ErrNoAgent) {
+			t.Errorf("Connect() without agent = %v, want ErrNoAgent", err)
+		}
+		var iwdErr *iwd.Error
+		if !errors.As(err, &iwdErr) || iwdErr.Message != "no agent registered" {
+			t.Errorf("Connect() without agent = %#v, want an *iwd.Error with the message", err)
+		}
+
+		srv.Handle("net.connman.iwd.Station.Scan", func(iwdtest.Call) ([]interface{}, error) {
+			return nil, iwdtest.Error("Busy", "scan in progress")
+		})
+		stations, err := i.Stations()
+		if err != nil {
+			t.Fatalf("Stations() = %v", err)
+		}
+		if err := stations[0].Scan(); !errors.Is(err, iwd.ErrBusy) {
+			t.Errorf("Scan() = %v, want ErrBusy", err)
+		}
+	})
+}
+
+func TestBackendAgent(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *iwdtest.Server, i *iwd.Iwd) {
+		station := srv.AddStation(srv.AddAdapter("phy0"), "wlan0", "02:00:00:00:00:01")
+		network := srv.AddNetwork(station, "Cafe", "psk", -6000)
+		agent := &testAgent{}
+		if err := i.RegisterAgent("/test/agent", agent); err != nil {
+			t.Fatalf("RegisterAgent() = %v", err)
+		}
+		n, err := iwd.NewNetwork(network, i)
+		if err != nil {
+			t.Fatalf("NewNetwork() = %v", err)
+		}
+		if err := n.Connect(); err != nil {
+			t.Fatalf("Connect() = %v", err)
+		}
+		if got := agent.Requests(); len(got) != 1 || got[0] != "Cafe" {
+			t.Errorf("agent was asked for %v, want [Cafe]", got)
+		}
+		if state, _ := srv.Property(station, "net.connman.iwd.Station", "State"); state != "connected" {
+			t.Errorf("station state = %v, want connected", state)
+		}
+		if err := i.UnregisterAgent("/test/agent"); err != nil {
+			t.Errorf("UnregisterAgent() = %v", err)
+		}
+	})
+}
+
+func TestBackendSignals(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *iwdtest.Server, i *iwd.Iwd) {
+		station := srv.AddStation(srv.AddAdapter("phy0"), "wlan0", "02:00:00:00:00:01")
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		added, err := i.SubscribeObjectAdded(ctx)
+		if err != nil {
+			t.Fatalf("SubscribeObjectAdded() = %v", err)
+		}
+		changed, err := i.SubscribePropertiesChanged(ctx)
+		if err != nil {
+			t.Fatalf("SubscribePropertiesChanged() = %v", err)
+		}
+		m, err := iwd.NewManager(ctx, i)
+		if err != nil {
+			t.Fatalf("NewManager() = %v", err)
+		}
+		defer m.Close()
+
+		srv.ResetCalls()
+		network := srv.AddNetwork(station, "Cafe", "open", -5000)
+		event := receive(t, added.Events)
+		if event.Path != network || event.Network == nil || event.Err != nil {
+			t.Fatalf("ObjectAdded = %+v, want network %s", event, network)
+		}
+		if event.Network.Name != "Cafe" {
+			t.Errorf("ObjectAdded.Network.Name = %q, want Cafe", event.Network.Name)
+		}
+		if calls := srv.Calls(); len(calls) != 0 {
+			t.Errorf("building the added network called %v", calls)
+		}
+
+		srv.SetStationState(station, "connecting")
+		change := receive(t, changed.Events)
+		if change.Path != station || change.Changed["State"].Value() != "connecting" {
+			t.Errorf("PropertiesChanged = %+v, want State connecting on %s", change, station)
+		}
+		eventually(t, "the manager to see the change", func() bool {
+			props, _ := m.Snapshot().Properties(station, "net.connman.iwd.Station")
+			return props["State"].Value() == "connecting" && len(m.Snapshot().Paths("net.connman.iwd.Network")) == 1
+		})
+	})
+}
+
+func TestMemoryBackendSender(t *testing.T) {
+	srv := iwdtest.NewMemoryServer()
+	defer srv.Close()
+	b := srv.Backend()
+	defer b.Close()
+	owners := make(chan string, 1)
+	if err := b.WatchService(owners); err != nil {
+		t.Fatalf("WatchService() = %v", err)
+	}
+	owner := <-owners
+	if owner == "" {
+		t.Fatal("WatchService() reported no owner for a running server")
+	}
+	if err := b.AddSignalMatch("", "net.connman.iwd.Test", "Ping"); err != nil {
+		t.Fatalf("AddSignalMatch() = %v", err)
+	}
+	signals := make(chan *dbus.Signal, 1)
+	b.Signal(signals)
+	if err := srv.Emit("/", "net.connman.iwd.Test.Ping"); err != nil {
+		t.Fatalf("Emit() = %v", err)
+	}
+	if sig := receive(t, signals); sig.Sender != owner {
+		t.Errorf("signal sent by %q, want the service owner %q", sig.Sender, owner)
+	}
+}
+
+func TestStationDebugEvents(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *iwdtest.Server, i *iwd.Iwd) {
+		station := srv.AddStation(srv.AddAdapter("phy0"), "wlan0", "02:00:00:00:00:01")
+		srv.AddInterface(station, "net.connman.iwd.StationDebug", map[string]interface{}{"AutoConnect": true})
+		debug, err := iwd.NewStationDebug(station, i)
+		if err != nil {
+			t.Fatalf("NewStationDebug() = %v", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		events, err := debug.SubscribeEvents(ctx)
+		if err != nil {
+			t.Fatalf("SubscribeEvents() = %v", err)
+		}
+		srv.Emit(station, "net.connman.iwd.StationDebug.Event", "ft-roam",
+			[]dbus.Variant{dbus.MakeVariant([]byte{2, 0, 0, 0, 1, 1}), dbus.MakeVariant(uint32(5))})
+		event := receive(t, events.Events)
+		if event.Name != iwd.FTRoamEvent || event.Address != "02:00:00:00:01:01" ||
+			len(event.Data) != 1 || event.Data[0] != uint32(5) {
+			t.Errorf("StationDebugEvent = %+v, want ft-roam to 02:00:00:00:01:01 with data [5]", event)
+		}
+		srv.Emit(station, "net.connman.iwd.StationDebug.Event", "roam-scan-triggered", []dbus.Variant{})
+		if event := receive(t, events.Events); event.Name != iwd.RoamScanTriggeredEvent || event.Address != "" || event.Data != nil {
+			t.Errorf("StationDebugEvent = %+v, want roam-scan-triggered without data", event)
+		}
+	})
+}
+
+func TestRecordAgentAndSignals(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *iwdtest.Server, i *iwd.Iwd) {
+		station := srv.AddStation(srv.AddAdapter("phy0"), "wlan0", "02:00:00:00:00:01")
+		network := srv.AddNetwork(station, "Cafe", "psk", -6000)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		added, err := i.SubscribeObjectAdded(ctx)
+		if err != nil {
+			t.Fatalf("SubscribeObjectAdded() = %v", err)
+		}
+		if err := i.RegisterAgent("/test/agent", &testAgent{}); err != nil {
+			t.Fatalf("RegisterAgent() = %v", err)
+		}
+		var buf bytes.Buffer
+		var mu sync.Mutex
+		i.Record(lockedWriter{&buf, &mu})
+		n, err := iwd.NewNetwork(network, i)
+		if err != nil {
+			t.Fatalf("NewNetwork() = %v", err)
+		}
+		if err := n.Connect(); err != nil {
+			t.Fatalf("Connect() = %v", err)
+		}
+		srv.AddKnownNetwork("Cafe", "psk")
+		receive(t, added.Events)
+		i.Record(nil)
+		if err := i.RecordErr(); err != nil {
+			t.Fatalf("RecordErr() = %v", err)
+		}
+
+		mu.Lock()
+		recording, err := iwd.ReadRecording(&buf)
+		mu.Unlock()
+		if err != nil {
+			t.Fatalf("ReadRecording() = %v", err)
+		}
+		var agentCall, signal bool
+		for _, m := range recording {
+			switch {
+			case m.Agent && m.Name == "net.connman.iwd.Agent.RequestPassphrase":
+				agentCall = m.Path == "/test/agent" && len(m.Reply) == 1 && m.Reply[0] == "secret"
+			case m.Signal && m.Name == "org.freedesktop.DBus.ObjectManager.InterfacesAdded":
+				signal = true
+			}
+		}
+		if !agentCall {
+			t.Errorf("recording %+v lacks RequestPassphrase answered with the secret", recording)
+		}
+		if !signal {
+			t.Errorf("recording %+v lacks the InterfacesAdded of the known network", recording)
+		}
+	})
+}
+
+// lockedWriter guards a buffer written by the recording
+// while the test reads it.
+type lockedWriter struct {
+	buf *bytes.Buffer
+	mu  *sync.Mutex
+}
+
+func (w lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func TestGetOrderedNetworksVanished(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *iwdtest.Server, i *iwd.Iwd) {
+		station := srv.AddStation(srv.AddAdapter("phy0"), "wlan0", "02:00:00:00:00:01")
+		cafe := srv.AddNetwork(station, "Cafe", "psk", -6000)
+		library := srv.AddNetwork(station, "Library", "open", -7000)
+		type orderedNetwork struct {
+			Path   dbus.ObjectPath
+			Signal int16
+		}
+		// The library goes out of range right after iwd
+		// listed it.
+		srv.Handle("net.connman.iwd.Station.GetOrderedNetworks", func(iwdtest.Call) ([]interface{}, error) {
+			srv.RemoveObject(library)
+			return []interface{}{[]orderedNetwork{{cafe, -6000}, {library, -7000}}}, nil
+		})
+		s, err := iwd.NewStation(station, i)
+		if err != nil {
+			t.Fatalf("NewStation() = %v", err)
+		}
+		networks, err := s.GetOrderedNetworks()
+		if err != nil {
+			t.Fatalf("GetOrderedNetworks() = %v", err)
+		}
+		if len(networks) != 1 || networks[0].Path != cafe || networks[0].SignalStrength != -6000 {
+			t.Errorf("GetOrderedNetworks() = %+v, want only %s", networks, cafe)
+		}
+	})
+}
+
+func TestNetworkConfiguration(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *iwdtest.Server, i *iwd.Iwd) {
+		station := srv.AddStation(srv.AddAdapter("phy0"), "wlan0", "02:00:00:00:00:01")
+		s, err := iwd.NewStation(station, i)
+		if err != nil {
+			t.Fatalf("NewStation() = %v", err)
+		}
+		if config, err := s.IPv4Configuration(); config != nil || err != nil {
+			t.Errorf("IPv4Configuration() before connecting = %+v, %v; want nil", config, err)
+		}
+		srv.AddInterface(station, "net.connman.iwd.IPv4Configuration", map[string]interface{}{
+			"Method":            "auto",
+			"Address":           "192.168.1.10",
+			"PrefixLength":      uint8(24),
+			"Gateway":           "192.168.1.1",
+			"DomainNameServers": []string{"192.168.1.1"},
+		})
+		srv.ResetCalls()
+		config, err := s.IPv4Configuration()
+		if err != nil {
+			t.Fatalf("IPv4Configuration() = %v", err)
+		}
+		if config == nil || config.Path != station || config.Method != iwd.AutoConfigurationMethod ||
+			config.Address != "192.168.1.10" || config.PrefixLength != 24 {
+			t.Errorf("IPv4Configuration() = %+v, want 192.168.1.10/24 on %s", config, station)
+		}
+		if calls := srv.CallsTo("org.freedesktop.DBus.ObjectManager.GetManagedObjects"); len(calls) != 0 {
+			t.Errorf("IPv4Configuration() listed all objects %d times", len(calls))
+		}
+		if config, err := s.IPv6Configuration(); config != nil || err != nil {
+			t.Errorf("IPv6Configuration() = %+v, %v; want nil", config, err)
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package iwdtest_test
+
+import (
+	"testing"
+
+	iwd "github.com/shtirlic/go-iwd"
+	"github.com/shtirlic/go-iwd/iwdtest"
+)
+
+const (
+	callGetAll                    = "org.freedesktop.DBus.Properties.GetAll"
+	callStationGetOrderedNetworks = "net.connman.iwd.Station.GetOrderedNetworks"
+)
+
+func TestGraphSingleCall(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *iwdtest.Server, i *iwd.Iwd) {
+		station := srv.AddStation(srv.AddAdapter("phy0"), "wlan0", "02:00:00:00:00:01")
+		for _, ssid := range []string{"Cafe", "Library", "Airport"} {
+			network := srv.AddNetwork(station, ssid, "psk", -6000)
+			srv.AddBasicServiceSet(network, "02:00:00:00:01:01")
+		}
+		s, err := iwd.NewStation(station, i)
+		if err != nil {
+			t.Fatalf("NewStation() = %v", err)
+		}
+
+		srv.ResetCalls()
+		networks, err := i.Networks()
+		if err != nil {
+			t.Fatalf("Networks() = %v", err)
+		}
+		if len(networks) != 3 {
+			t.Fatalf("Networks() = %+v, want three networks", networks)
+		}
+		for _, n := range networks {
+			if n.Device == nil || n.Device.Path != station || len(n.ExtendedServiceSet) != 1 {
+				t.Errorf("Networks() built %+v, want a device and a BSS", n)
+			}
+		}
+		if calls := srv.CallsTo(callGetManagedObjects); len(calls) != 1 {
+			t.Errorf("Networks() listed all objects %d times, want once", len(calls))
+		}
+		if calls := srv.CallsTo(callGetAll); len(calls) != 0 {
+			t.Errorf("Networks() read the properties of %d objects, want none", len(calls))
+		}
+
+		srv.ResetCalls()
+		ordered, err := s.GetOrderedNetworks()
+		if err != nil {
+			t.Fatalf("GetOrderedNetworks() = %v", err)
+		}
+		if len(ordered) != 3 {
+			t.Errorf("GetOrderedNetworks() = %+v, want three networks", ordered)
+		}
+		calls := srv.Calls()
+		if len(calls) != 2 || calls[0].Method != callStationGetOrderedNetworks || calls[1].Method != callGetManagedObjects {
+			t.Errorf("GetOrderedNetworks() made the calls %+v, want GetOrderedNetworks and GetManagedObjects", calls)
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package iwdtest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	iwd "github.com/shtirlic/go-iwd"
+	"github.com/shtirlic/go-iwd/iwdtest"
+)
+
+func TestSnapshotPropertiesCopy(t *testing.T) {
+	srv := iwdtest.NewMemoryServer()
+	defer srv.Close()
+	i := iwd.NewIwdWithBackend(srv.Backend())
+	defer i.Close()
+	station := srv.AddStation(srv.AddAdapter("phy0"), "wlan0", "02:00:00:00:00:01")
+	m, err := iwd.NewManager(context.Background(), i)
+	if err != nil {
+		t.Fatalf("NewManager() = %v", err)
+	}
+	defer m.Close()
+	snapshot := m.Snapshot()
+	props, ok := snapshot.Properties(station, "net.connman.iwd.Station")
+	if !ok {
+		t.Fatal("Properties() found no station")
+	}
+	props["State"] = dbus.MakeVariant("connected")
+	delete(props, "Scanning")
+	for _, s := range []*iwd.Snapshot{snapshot, m.Snapshot()} {
+		props, _ := s.Properties(station, "net.connman.iwd.Station")
+		if props["State"].Value() != "disconnected" || props["Scanning"].Value() != false {
+			t.Errorf("Properties() = %v after changing a copy, want the station unchanged", props)
+		}
+	}
+	if _, ok := snapshot.Properties(station, "net.connman.iwd.Network"); ok {
+		t.Error("Properties() found a network on the station")
+	}
+}
+
+func TestManagerPendingReplay(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *iwdtest.Server, i *iwd.Iwd) {
+		station := srv.AddStation(srv.AddAdapter("phy0"), "wlan0", "02:00:00:00:00:01")
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		changed, err := i.SubscribePropertiesChanged(ctx)
+		if err != nil {
+			t.Fatalf("SubscribePropertiesChanged() = %v", err)
+		}
+		// The station starts connecting while iwd answers
+		// GetManagedObjects, so the tree is older than the
+		// change.
+		tree := map[dbus.ObjectPath]map[string]map[string]dbus.Variant{
+			station: {
+				"net.connman.iwd.Station": {
+					"State":    dbus.MakeVariant("disconnected"),
+					"Scanning": dbus.MakeVariant(false),
+				},
+			},
+		}
+		srv.Handle("org.freedesktop.DBus.ObjectManager.GetManagedObjects", func(iwdtest.Call) ([]interface{}, error) {
+			srv.SetStationState(station, "connecting")
+			// Once the change of the device arrives, the
+			// Iwd has handled the change of the station.
+			srv.SetProperty(station, "net.connman.iwd.Device", "Powered", false)
+			for {
+				if change := receive(t, changed.Events); change.Interface == "net.connman.iwd.Device" {
+					break
+				}
+			}
+			return []interface{}{tree}, nil
+		})
+		m, err := iwd.NewManager(ctx, i)
+		if err != nil {
+			t.Fatalf("NewManager() = %v", err)
+		}
+		defer m.Close()
+		props, ok := m.Snapshot().Properties(station, "net.connman.iwd.Station")
+		if !ok || props["State"].Value() != "connecting" {
+			t.Errorf("station properties = %v, want the State connecting received while loading", props)
+		}
+	})
+}
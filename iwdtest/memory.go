@@ -0,0 +1,388 @@
+package iwdtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+	iwd "github.com/shtirlic/go-iwd"
+	"github.com/shtirlic/go-iwd/utils"
+)
+
+// NewMemoryServer returns a Server without a bus, reached
+// only through the backends returned by Server.Backend.
+// It needs no dbus-daemon, so tests using it are pure Go.
+//
+//	srv := iwdtest.NewMemoryServer()
+//	defer srv.Close()
+//	i := iwd.NewIwdWithBackend(srv.Backend())
+func NewMemoryServer() *Server {
+	return newServer()
+}
+
+// Backend returns a new iwd.Backend connected to s in
+// memory, for use with iwd.NewIwdWithBackend.  Calls and
+// signals go through the same code as on the bus and all
+// values are encoded to the D-Bus wire format and back, so
+// the Iwd sees the types it would get from a real bus.
+// Each backend acts as a separate client of the service.
+func (s *Server) Backend() iwd.Backend {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nclients++
+	b := &memoryBackend{
+		s:       s,
+		name:    fmt.Sprintf(":memory.%d", s.nclients),
+		matches: make(map[memoryMatch]int),
+		exports: make(map[dbus.ObjectPath]map[string]interface{}),
+	}
+	s.clients[b.name] = b
+	return b
+}
+
+// client returns the backend with the unique name name.
+func (s *Server) client(name string) (*memoryBackend, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.clients[name]
+	return b, ok
+}
+
+type memoryMatch struct {
+	path   dbus.ObjectPath
+	iface  string
+	member string
+}
+
+type memoryBackend struct {
+	s    *Server
+	name string // Unique name the service sees as sender
+
+	mu       sync.Mutex
+	matches  map[memoryMatch]int
+	signals  []chan<- *dbus.Signal
+	watchers []chan<- string
+	exports  map[dbus.ObjectPath]map[string]interface{}
+
+	// watchMu orders sends to the watchers against closing
+	// them in Close, without holding mu while a send blocks.
+	watchMu sync.Mutex
+	closed  bool
+}
+
+func (b *memoryBackend) CallMethod(ctx context.Context, path dbus.ObjectPath, method string,
+	args ...interface{}) (*dbus.Call, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	b.s.mu.Lock()
+	stopped := b.s.stopped
+	b.s.mu.Unlock()
+	if stopped {
+		return nil, dbus.Error{
+			Name: "org.freedesktop.DBus.Error.ServiceUnknown",
+			Body: []interface{}{"The name " + iwdService + " was not provided by any .service files"},
+		}
+	}
+	if !b.s.exists(path) {
+		return nil, dbus.MakeNoObjectError(path)
+	}
+	args, err := wireCopy(args)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := b.s.dispatch(Call{Sender: b.name, Path: path, Method: method, Args: args})
+	if err != nil {
+		return nil, replyError(err)
+	}
+	if reply, err = wireCopy(reply); err != nil {
+		return nil, err
+	}
+	return &dbus.Call{Destination: iwdService, Path: path, Method: method, Args: args, Body: reply}, nil
+}
+
+func (b *memoryBackend) GetProperty(ctx context.Context, path dbus.ObjectPath, iface, name string) (dbus.Variant, error) {
+	call, err := b.CallMethod(ctx, path, dbusPropertiesIface+".Get", iface, name)
+	if err != nil {
+		return dbus.Variant{}, err
+	}
+	var v dbus.Variant
+	if err := call.Store(&v); err != nil {
+		return dbus.Variant{}, err
+	}
+	return v, nil
+}
+
+func (b *memoryBackend) GetAllProperties(ctx context.Context, path dbus.ObjectPath, iface string) (utils.DBusMapVariant, error) {
+	call, err := b.CallMethod(ctx, path, dbusPropertiesIface+".GetAll", iface)
+	if err != nil {
+		return nil, err
+	}
+	var props utils.DBusMapVariant
+	if err := call.Store(&props); err != nil {
+		return nil, err
+	}
+	return props, nil
+}
+
+func (b *memoryBackend) SetProperty(ctx context.Context, path dbus.ObjectPath, iface, name string,
+	value interface{}) error {
+
+	if _, err := b.CallMethod(ctx, path, dbusPropertiesIface+".Set", iface, name, dbus.MakeVariant(value)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (b *memoryBackend) GetManagedObjects(ctx context.Context) (utils.DBusRetValues, error) {
+	call, err := b.CallMethod(ctx, "/", dbusObjectManagerIface+".GetManagedObjects")
+	if err != nil {
+		return nil, err
+	}
+	var objects utils.DBusRetValues
+	if err := call.Store(&objects); err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+func (b *memoryBackend) AddSignalMatch(path dbus.ObjectPath, iface, member string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.matches[memoryMatch{path, iface, member}]++
+	return nil
+}
+
+func (b *memoryBackend) RemoveSignalMatch(path dbus.ObjectPath, iface, member string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	m := memoryMatch{path, iface, member}
+	if b.matches[m]--; b.matches[m] <= 0 {
+		delete(b.matches, m)
+	}
+	return nil
+}
+
+func (b *memoryBackend) Signal(ch chan<- *dbus.Signal) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.signals = append(b.signals, ch)
+}
+
+func (b *memoryBackend) WatchService(ch chan<- string) error {
+	b.s.mu.Lock()
+	stopped := b.s.stopped
+	b.s.mu.Unlock()
+	owner := b.s.owner()
+	if stopped {
+		owner = ""
+	}
+	b.watchMu.Lock()
+	defer b.watchMu.Unlock()
+	if b.closed {
+		return net.ErrClosed
+	}
+	b.mu.Lock()
+	b.watchers = append(b.watchers, ch)
+	b.mu.Unlock()
+	ch <- owner
+	return nil
+}
+
+func (b *memoryBackend) Export(v interface{}, path dbus.ObjectPath, iface string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if v == nil {
+		delete(b.exports[path], iface)
+		return nil
+	}
+	if b.exports[path] == nil {
+		b.exports[path] = make(map[string]interface{})
+	}
+	b.exports[path][iface] = v
+	return nil
+}
+
+// Close disconnects the backend from the service, which
+// forgets the agent it registered.
+func (b *memoryBackend) Close() error {
+	b.s.mu.Lock()
+	delete(b.s.clients, b.name)
+	if b.s.agent != nil && b.s.agent.sender == b.name {
+		b.s.agent = nil
+	}
+	b.s.mu.Unlock()
+	b.watchMu.Lock()
+	defer b.watchMu.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	b.mu.Lock()
+	watchers := b.watchers
+	b.signals = nil
+	b.watchers = nil
+	b.mu.Unlock()
+	for _, ch := range watchers {
+		close(ch)
+	}
+	return nil
+}
+
+// ownerChanged tells the backends watching the service
+// that its owner changed.
+func (s *Server) ownerChanged(owner string) {
+	s.mu.Lock()
+	clients := make([]*memoryBackend, 0, len(s.clients))
+	for _, b := range s.clients {
+		clients = append(clients, b)
+	}
+	s.mu.Unlock()
+	for _, b := range clients {
+		b.notifyWatchers(owner)
+	}
+}
+
+// notifyWatchers sends owner to the watchers of b.  The
+// watchers are copied under mu, which is released before
+// sending so that a watcher may call into b meanwhile.
+func (b *memoryBackend) notifyWatchers(owner string) {
+	b.watchMu.Lock()
+	defer b.watchMu.Unlock()
+	b.mu.Lock()
+	watchers := append([]chan<- string(nil), b.watchers...)
+	b.mu.Unlock()
+	for _, ch := range watchers {
+		ch <- owner
+	}
+}
+
+// deliver sends a signal emitted by the service to the
+// channels of b if it matches one of its subscriptions.
+func (b *memoryBackend) deliver(path dbus.ObjectPath, name string, values []interface{}) error {
+	dot := strings.LastIndex(name, ".")
+	iface, member := name[:dot], name[dot+1:]
+	b.mu.Lock()
+	matched := b.matches[memoryMatch{path, iface, member}] > 0 || b.matches[memoryMatch{"", iface, member}] > 0
+	channels := append([]chan<- *dbus.Signal(nil), b.signals...)
+	b.mu.Unlock()
+	if !matched {
+		return nil
+	}
+	body, err := wireCopy(values)
+	if err != nil {
+		return err
+	}
+	for _, ch := range channels {
+		ch <- &dbus.Signal{Sender: b.s.owner(), Path: path, Name: name, Body: body}
+	}
+	return nil
+}
+
+// callExport calls member of an object exported by the
+// client the way godbus does: arguments are stored into
+// the method parameters and a trailing *dbus.Error result
+// is returned as the error.
+func (b *memoryBackend) callExport(path dbus.ObjectPath, iface, member string, args []interface{}) ([]interface{}, error) {
+	b.mu.Lock()
+	v, ok := b.exports[path][iface]
+	b.mu.Unlock()
+	if !ok {
+		return nil, dbus.MakeNoObjectError(path)
+	}
+	method := reflect.ValueOf(v).MethodByName(member)
+	if !method.IsValid() {
+		return nil, dbus.MakeUnknownMethodError(member)
+	}
+	args, err := wireCopy(args)
+	if err != nil {
+		return nil, err
+	}
+	t := method.Type()
+	if t.NumIn() != len(args) {
+		return nil, *dbus.MakeFailedError(fmt.Errorf("%s.%s takes %d arguments, got %d", iface, member, t.NumIn(), len(args)))
+	}
+	in := make([]reflect.Value, t.NumIn())
+	ptrs := make([]interface{}, t.NumIn())
+	for n := range in {
+		in[n] = reflect.New(t.In(n))
+		ptrs[n] = in[n].Interface()
+	}
+	if err := dbus.Store(args, ptrs...); err != nil {
+		return nil, *dbus.MakeFailedError(err)
+	}
+	for n := range in {
+		in[n] = in[n].Elem()
+	}
+	out := method.Call(in)
+	var reply []interface{}
+	for n, v := range out {
+		if dbusErr, ok := v.Interface().(*dbus.Error); ok && n == len(out)-1 {
+			if dbusErr != nil {
+				return nil, replyError(dbusErr)
+			}
+			continue
+		}
+		reply = append(reply, v.Interface())
+	}
+	return wireCopy(reply)
+}
+
+// replyError converts an error returned by a method to the
+// dbus.Error a caller receives, as godbus does when sending
+// error replies.
+func replyError(err error) error {
+	var e dbus.Error
+	switch err := err.(type) {
+	case dbus.Error:
+		e = err
+	case *dbus.Error:
+		e = *err
+	case dbus.DBusError:
+		name, body := err.DBusError()
+		e = dbus.Error{Name: name, Body: body}
+	default:
+		e = *dbus.MakeFailedError(err)
+	}
+	body, copyErr := wireCopy(e.Body)
+	if copyErr != nil {
+		return copyErr
+	}
+	e.Body = body
+	return e
+}
+
+// wireCopy encodes values as the body of a D-Bus message
+// and decodes them again, so that they have the types
+// godbus produces for received messages.
+func wireCopy(values []interface{}) ([]interface{}, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	msg := &dbus.Message{
+		Type: dbus.TypeSignal,
+		Headers: map[dbus.HeaderField]dbus.Variant{
+			dbus.FieldPath:      dbus.MakeVariant(dbus.ObjectPath("/")),
+			dbus.FieldInterface: dbus.MakeVariant(iwdService),
+			dbus.FieldMember:    dbus.MakeVariant("Copy"),
+			dbus.FieldSignature: dbus.MakeVariant(dbus.SignatureOf(values...)),
+		},
+		Body: values,
+	}
+	var buf bytes.Buffer
+	if err := msg.EncodeTo(&buf, binary.LittleEndian); err != nil {
+		return nil, err
+	}
+	copied, err := dbus.DecodeMessage(&buf)
+	if err != nil {
+		return nil, err
+	}
+	return copied.Body, nil
+}
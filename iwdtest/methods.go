@@ -0,0 +1,207 @@
+package iwdtest
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// builtinMethods implement the default behaviour of the
+// fake service.  They can be replaced with Server.Handle.
+var builtinMethods = map[string]func(*Server, Call) ([]interface{}, error){
+	dbusObjectManagerIface + ".GetManagedObjects": getManagedObjects,
+	dbusPropertiesIface + ".GetAll":               getAllProperties,
+	dbusPropertiesIface + ".Get":                  getProperty,
+	dbusPropertiesIface + ".Set":                  setProperty,
+	iwdService + ".Daemon.GetInfo":                daemonGetInfo,
+	iwdService + ".AgentManager.RegisterAgent":    registerAgent,
+	iwdService + ".AgentManager.UnregisterAgent":  unregisterAgent,
+	iwdStationIface + ".Scan":                     stationScan,
+	iwdStationIface + ".Disconnect":               stationDisconnect,
+	iwdStationIface + ".GetOrderedNetworks":       stationGetOrderedNetworks,
+	iwdNetworkIface + ".Connect":                  networkConnect,
+	iwdKnownNetworkIface + ".Forget":              knownNetworkForget,
+	iwdWSCIface + ".GeneratePin":                  wscGeneratePin,
+}
+
+func getManagedObjects(s *Server, c Call) ([]interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	objects := make(map[dbus.ObjectPath]map[string]map[string]dbus.Variant, len(s.objects))
+	for p, ifaces := range s.objects {
+		objects[p] = make(map[string]map[string]dbus.Variant, len(ifaces))
+		for iface, props := range ifaces {
+			objects[p][iface] = props
+		}
+	}
+	return []interface{}{objects}, nil
+}
+
+func getAllProperties(s *Server, c Call) ([]interface{}, error) {
+	iface, _ := c.Args[0].(string)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	props, ok := s.objects[c.Path][iface]
+	if !ok {
+		return nil, dbus.MakeUnknownInterfaceError(iface)
+	}
+	return []interface{}{props}, nil
+}
+
+func getProperty(s *Server, c Call) ([]interface{}, error) {
+	iface, _ := c.Args[0].(string)
+	name, _ := c.Args[1].(string)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	props, ok := s.objects[c.Path][iface]
+	if !ok {
+		return nil, dbus.MakeUnknownInterfaceError(iface)
+	}
+	v, ok := props[name]
+	if !ok {
+		return nil, Error("InvalidArguments", "unknown property "+name)
+	}
+	return []interface{}{v}, nil
+}
+
+func setProperty(s *Server, c Call) ([]interface{}, error) {
+	iface, _ := c.Args[0].(string)
+	name, _ := c.Args[1].(string)
+	v, _ := c.Args[2].(dbus.Variant)
+	if _, ok := s.Property(c.Path, iface, name); !ok {
+		return nil, Error("InvalidArguments", "unknown property "+name)
+	}
+	s.SetProperty(c.Path, iface, name, v.Value())
+	return nil, nil
+}
+
+func daemonGetInfo(s *Server, c Call) ([]interface{}, error) {
+	return []interface{}{map[string]dbus.Variant{
+		"Version":                     dbus.MakeVariant("iwdtest"),
+		"StateDirectory":              dbus.MakeVariant("/var/lib/iwd"),
+		"NetworkConfigurationEnabled": dbus.MakeVariant(false),
+	}}, nil
+}
+
+func registerAgent(s *Server, c Call) ([]interface{}, error) {
+	path, _ := c.Args[0].(dbus.ObjectPath)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.agent != nil {
+		return nil, Error("AlreadyExists", "agent already registered")
+	}
+	s.agent = &registeredAgent{sender: c.Sender, path: path}
+	return nil, nil
+}
+
+func unregisterAgent(s *Server, c Call) ([]interface{}, error) {
+	path, _ := c.Args[0].(dbus.ObjectPath)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.agent == nil || s.agent.sender != c.Sender || s.agent.path != path {
+		return nil, Error("NotFound", "agent not registered")
+	}
+	s.agent = nil
+	return nil, nil
+}
+
+func stationScan(s *Server, c Call) ([]interface{}, error) {
+	s.SetProperty(c.Path, iwdStationIface, "Scanning", true)
+	s.SetProperty(c.Path, iwdStationIface, "Scanning", false)
+	return nil, nil
+}
+
+func stationDisconnect(s *Server, c Call) ([]interface{}, error) {
+	network, ok := s.Property(c.Path, iwdStationIface, "ConnectedNetwork")
+	if !ok {
+		return nil, Error("NotConnected", "station is not connected")
+	}
+	s.SetStationState(c.Path, "disconnecting")
+	s.SetProperty(network.(dbus.ObjectPath), iwdNetworkIface, "Connected", false)
+	s.RemoveProperty(c.Path, iwdStationIface, "ConnectedNetwork")
+	s.SetStationState(c.Path, "disconnected")
+	return nil, nil
+}
+
+type orderedNetwork struct {
+	Path   dbus.ObjectPath
+	Signal int16
+}
+
+func stationGetOrderedNetworks(s *Server, c Call) ([]interface{}, error) {
+	paths := s.networks(func(props map[string]dbus.Variant) bool {
+		return props["Device"].Value() == c.Path
+	})
+	networks := []orderedNetwork{}
+	s.mu.Lock()
+	for _, p := range paths {
+		networks = append(networks, orderedNetwork{p, s.signals[p]})
+	}
+	s.mu.Unlock()
+	sort.SliceStable(networks, func(a, b int) bool { return networks[a].Signal > networks[b].Signal })
+	return []interface{}{networks}, nil
+}
+
+// networkConnect asks the registered agent for a
+// passphrase if the network is secured and not known yet,
+// then marks it connected and known.
+func networkConnect(s *Server, c Call) ([]interface{}, error) {
+	name, _ := s.Property(c.Path, iwdNetworkIface, "Name")
+	typ, _ := s.Property(c.Path, iwdNetworkIface, "Type")
+	station, ok := s.Property(c.Path, iwdNetworkIface, "Device")
+	if !ok {
+		return nil, Error("NotFound", "unknown network")
+	}
+	if _, known := s.Property(c.Path, iwdNetworkIface, "KnownNetwork"); !known {
+		switch typ {
+		case "psk", "wep":
+			if _, err := s.CallAgent("RequestPassphrase", c.Path); err != nil {
+				if errorName(err) == iwdService+".NoAgent" {
+					return nil, err
+				}
+				return nil, Error("Aborted", err.Error())
+			}
+		case "8021x":
+			return nil, Error("NotConfigured", "network is not provisioned")
+		}
+		s.AddKnownNetwork(name.(string), typ.(string))
+	}
+	if previous, ok := s.Property(station.(dbus.ObjectPath), iwdStationIface, "ConnectedNetwork"); ok {
+		s.SetProperty(previous.(dbus.ObjectPath), iwdNetworkIface, "Connected", false)
+	}
+	s.SetStationState(station.(dbus.ObjectPath), "connecting")
+	s.SetProperty(station.(dbus.ObjectPath), iwdStationIface, "ConnectedNetwork", c.Path)
+	s.SetProperty(c.Path, iwdNetworkIface, "Connected", true)
+	s.SetStationState(station.(dbus.ObjectPath), "connected")
+	return nil, nil
+}
+
+func knownNetworkForget(s *Server, c Call) ([]interface{}, error) {
+	for _, network := range s.networks(func(props map[string]dbus.Variant) bool {
+		return props["KnownNetwork"].Value() == c.Path
+	}) {
+		s.RemoveProperty(network, iwdNetworkIface, "KnownNetwork")
+	}
+	s.RemoveObject(c.Path)
+	return nil, nil
+}
+
+func wscGeneratePin(s *Server, c Call) ([]interface{}, error) {
+	return []interface{}{"12345670"}, nil
+}
+
+// errorName returns the D-Bus name of err, which is a
+// *dbus.Error when made by Error and a dbus.Error when
+// received from a client.
+func errorName(err error) string {
+	var ptr *dbus.Error
+	if errors.As(err, &ptr) {
+		return ptr.Name
+	}
+	var val dbus.Error
+	if errors.As(err, &val) {
+		return val.Name
+	}
+	return ""
+}
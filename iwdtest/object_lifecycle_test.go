@@ -0,0 +1,86 @@
+package iwdtest_test
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	iwd "github.com/shtirlic/go-iwd"
+	"github.com/shtirlic/go-iwd/iwdtest"
+)
+
+func TestObjectAdded(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *iwdtest.Server, i *iwd.Iwd) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		added, err := i.SubscribeObjectAdded(ctx)
+		if err != nil {
+			t.Fatalf("SubscribeObjectAdded() = %v", err)
+		}
+
+		adapter := srv.AddAdapter("phy0")
+		event := receive(t, added.Events)
+		if event.Path != adapter || event.Adapter == nil || event.Adapter.Name != "phy0" || event.Err != nil {
+			t.Errorf("ObjectAdded = %+v, want adapter phy0", event)
+		}
+
+		station := srv.AddStation(adapter, "wlan0", "02:00:00:00:00:01")
+		event = receive(t, added.Events)
+		ifaces := append([]string(nil), event.Interfaces...)
+		sort.Strings(ifaces)
+		want := []string{"net.connman.iwd.Device", "net.connman.iwd.SimpleConfiguration", "net.connman.iwd.Station"}
+		if event.Path != station || !reflect.DeepEqual(ifaces, want) {
+			t.Errorf("ObjectAdded = %+v, want %v on %s", event, want, station)
+		}
+		if event.Device == nil || event.Device.Name != "wlan0" || event.Station == nil || event.Station.State != iwd.DisconnectedState {
+			t.Errorf("ObjectAdded = %+v, want device wlan0 and a disconnected station", event)
+		}
+		if event.Device != nil {
+			// The adapter is not part of the signal and is
+			// read from iwd.
+			if adapter, err := event.Device.Adapter.Resolve(); err != nil || adapter.Name != "phy0" {
+				t.Errorf("Device.Adapter.Resolve() = %+v, %v; want phy0", adapter, err)
+			}
+		}
+
+		known := srv.AddKnownNetwork("Cafe", "psk")
+		event = receive(t, added.Events)
+		if event.Path != known || event.KnownNetwork == nil || event.KnownNetwork.Name != "Cafe" {
+			t.Errorf("ObjectAdded = %+v, want known network Cafe", event)
+		}
+
+		srv.Emit("/", "org.freedesktop.DBus.ObjectManager.InterfacesAdded", dbus.ObjectPath("/net/connman/iwd/phy0/9"),
+			map[string]map[string]dbus.Variant{
+				"net.connman.iwd.Station": {"State": dbus.MakeVariant(uint32(1))},
+			})
+		if event := receive(t, added.Events); event.Err == nil || event.Station != nil {
+			t.Errorf("ObjectAdded = %+v for a malformed station, want Err", event)
+		}
+	})
+}
+
+func TestObjectRemoved(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *iwdtest.Server, i *iwd.Iwd) {
+		station := srv.AddStation(srv.AddAdapter("phy0"), "wlan0", "02:00:00:00:00:01")
+		network := srv.AddNetwork(station, "Cafe", "psk", -6000)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		removed, err := i.SubscribeObjectRemoved(ctx)
+		if err != nil {
+			t.Fatalf("SubscribeObjectRemoved() = %v", err)
+		}
+
+		srv.RemoveObject(network)
+		event := receive(t, removed.Events)
+		if event.Path != network || !reflect.DeepEqual(event.Interfaces, []string{"net.connman.iwd.Network"}) {
+			t.Errorf("ObjectRemoved = %+v, want the network %s", event, network)
+		}
+		srv.RemoveInterface(station, "net.connman.iwd.Station")
+		event = receive(t, removed.Events)
+		if event.Path != station || !reflect.DeepEqual(event.Interfaces, []string{"net.connman.iwd.Station"}) {
+			t.Errorf("ObjectRemoved = %+v, want the Station interface of %s", event, station)
+		}
+	})
+}
@@ -0,0 +1,301 @@
+package iwdtest
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	iwdAdapterIface      = iwdService + ".Adapter"
+	iwdDeviceIface       = iwdService + ".Device"
+	iwdStationIface      = iwdService + ".Station"
+	iwdNetworkIface      = iwdService + ".Network"
+	iwdKnownNetworkIface = iwdService + ".KnownNetwork"
+	iwdBSSIface          = iwdService + ".BasicServiceSet"
+	iwdWSCIface          = iwdService + ".SimpleConfiguration"
+	iwdAgentIface        = iwdService + ".Agent"
+)
+
+// AddObject adds an object implementing the interfaces in
+// ifaces, each mapped to its properties, and emits
+// InterfacesAdded.  Interfaces of an existing object are
+// replaced.
+func (s *Server) AddObject(path dbus.ObjectPath, ifaces map[string]map[string]interface{}) {
+	added := make(map[string]map[string]dbus.Variant, len(ifaces))
+	for iface, props := range ifaces {
+		added[iface] = make(map[string]dbus.Variant, len(props))
+		for name, v := range props {
+			added[iface][name] = dbus.MakeVariant(v)
+		}
+	}
+	s.mu.Lock()
+	if s.objects[path] == nil {
+		s.objects[path] = make(map[string]map[string]dbus.Variant)
+	}
+	for iface, props := range added {
+		s.objects[path][iface] = props
+	}
+	s.mu.Unlock()
+	s.Emit("/", dbusObjectManagerIface+".InterfacesAdded", path, added)
+}
+
+// AddInterface adds iface with props to the object at path
+// and emits InterfacesAdded.
+func (s *Server) AddInterface(path dbus.ObjectPath, iface string, props map[string]interface{}) {
+	s.AddObject(path, map[string]map[string]interface{}{iface: props})
+}
+
+// RemoveInterface removes iface from the object at path
+// and emits InterfacesRemoved.  The object is removed with
+// its last interface.
+func (s *Server) RemoveInterface(path dbus.ObjectPath, iface string) {
+	s.mu.Lock()
+	if _, ok := s.objects[path][iface]; !ok {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.objects[path], iface)
+	if len(s.objects[path]) == 0 {
+		delete(s.objects, path)
+	}
+	s.mu.Unlock()
+	s.Emit("/", dbusObjectManagerIface+".InterfacesRemoved", path, []string{iface})
+}
+
+// RemoveObject removes the object at path and emits
+// InterfacesRemoved.
+func (s *Server) RemoveObject(path dbus.ObjectPath) {
+	s.mu.Lock()
+	var ifaces []string
+	for iface := range s.objects[path] {
+		ifaces = append(ifaces, iface)
+	}
+	delete(s.objects, path)
+	delete(s.signals, path)
+	s.mu.Unlock()
+	if len(ifaces) > 0 {
+		sort.Strings(ifaces)
+		s.Emit("/", dbusObjectManagerIface+".InterfacesRemoved", path, ifaces)
+	}
+}
+
+// HasInterface reports whether the object at path
+// implements iface.
+func (s *Server) HasInterface(path dbus.ObjectPath, iface string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.objects[path][iface]
+	return ok
+}
+
+// Property returns the value of a property.
+func (s *Server) Property(path dbus.ObjectPath, iface, name string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.objects[path][iface][name]
+	if !ok {
+		return nil, false
+	}
+	return v.Value(), true
+}
+
+// SetProperty sets a property and emits PropertiesChanged.
+// It does nothing if the object does not implement iface.
+func (s *Server) SetProperty(path dbus.ObjectPath, iface, name string, value interface{}) {
+	v := dbus.MakeVariant(value)
+	if !s.updateProperty(path, iface, name, &v) {
+		return
+	}
+	s.Emit(path, dbusPropertiesIface+".PropertiesChanged", iface,
+		map[string]dbus.Variant{name: v}, []string{})
+}
+
+// RemoveProperty removes an optional property and emits
+// PropertiesChanged listing it as invalidated.
+func (s *Server) RemoveProperty(path dbus.ObjectPath, iface, name string) {
+	if _, ok := s.Property(path, iface, name); !ok {
+		return
+	}
+	if !s.updateProperty(path, iface, name, nil) {
+		return
+	}
+	s.Emit(path, dbusPropertiesIface+".PropertiesChanged", iface,
+		map[string]dbus.Variant{}, []string{name})
+}
+
+// updateProperty replaces the property map instead of
+// modifying it, so maps handed out by GetManagedObjects
+// never change.
+func (s *Server) updateProperty(path dbus.ObjectPath, iface, name string, v *dbus.Variant) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	props, ok := s.objects[path][iface]
+	if !ok {
+		return false
+	}
+	updated := make(map[string]dbus.Variant, len(props)+1)
+	for k, pv := range props {
+		updated[k] = pv
+	}
+	if v == nil {
+		delete(updated, name)
+	} else {
+		updated[name] = *v
+	}
+	s.objects[path][iface] = updated
+	return true
+}
+
+// AddAdapter adds a powered adapter supporting all device
+// modes and returns its path.
+func (s *Server) AddAdapter(name string) dbus.ObjectPath {
+	s.mu.Lock()
+	path := dbus.ObjectPath(fmt.Sprintf("%s/phy%d", iwdObjPath, s.phys))
+	s.phys++
+	s.mu.Unlock()
+	s.AddInterface(path, iwdAdapterIface, map[string]interface{}{
+		"Name":           name,
+		"Powered":        true,
+		"Model":          "iwdtest",
+		"Vendor":         "iwdtest",
+		"SupportedModes": []string{"station", "ap", "ad-hoc"},
+	})
+	return path
+}
+
+// AddStation adds a powered device in station mode to
+// adapter, with the Station and SimpleConfiguration
+// interfaces, and returns its path.  The station starts
+// out disconnected.
+func (s *Server) AddStation(adapter dbus.ObjectPath, name, address string) dbus.ObjectPath {
+	s.mu.Lock()
+	s.devices++
+	path := dbus.ObjectPath(fmt.Sprintf("%s/%d", adapter, s.devices))
+	s.mu.Unlock()
+	s.AddObject(path, map[string]map[string]interface{}{
+		iwdDeviceIface: {
+			"Name":    name,
+			"Address": address,
+			"Powered": true,
+			"Adapter": adapter,
+			"Mode":    "station",
+		},
+		iwdStationIface: {
+			"State":    "disconnected",
+			"Scanning": false,
+		},
+		iwdWSCIface: {},
+	})
+	return path
+}
+
+// AddNetwork adds a network of type typ ("open", "psk",
+// "8021x", ...) seen by station with the given signal
+// strength in 100 * dBm, and returns its path.  If a
+// matching known network exists, the network refers to it.
+func (s *Server) AddNetwork(station dbus.ObjectPath, ssid, typ string, signal int16) dbus.ObjectPath {
+	path := dbus.ObjectPath(fmt.Sprintf("%s/%s_%s", station, hex.EncodeToString([]byte(ssid)), typ))
+	props := map[string]interface{}{
+		"Name":               ssid,
+		"Connected":          false,
+		"Device":             station,
+		"Type":               typ,
+		"ExtendedServiceSet": []dbus.ObjectPath{},
+	}
+	known := knownNetworkPath(ssid, typ)
+	if s.HasInterface(known, iwdKnownNetworkIface) {
+		props["KnownNetwork"] = known
+	}
+	s.mu.Lock()
+	s.signals[path] = signal
+	s.mu.Unlock()
+	s.AddInterface(path, iwdNetworkIface, props)
+	return path
+}
+
+// AddBasicServiceSet adds a BSS with the hardware address
+// address to the ExtendedServiceSet of network and returns
+// its path.
+func (s *Server) AddBasicServiceSet(network dbus.ObjectPath, address string) dbus.ObjectPath {
+	path := dbus.ObjectPath(fmt.Sprintf("%s/%s", network, strings.ReplaceAll(address, ":", "")))
+	s.AddInterface(path, iwdBSSIface, map[string]interface{}{
+		"Address": address,
+	})
+	ess, _ := s.Property(network, iwdNetworkIface, "ExtendedServiceSet")
+	paths, _ := ess.([]dbus.ObjectPath)
+	s.SetProperty(network, iwdNetworkIface, "ExtendedServiceSet", append(append([]dbus.ObjectPath(nil), paths...), path))
+	return path
+}
+
+// SetSignalStrength changes the signal strength reported
+// for network by GetOrderedNetworks.
+func (s *Server) SetSignalStrength(network dbus.ObjectPath, signal int16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.signals[network] = signal
+}
+
+// AddKnownNetwork adds a known network and returns its
+// path.  Networks with the same SSID and type are updated
+// to refer to it.
+func (s *Server) AddKnownNetwork(ssid, typ string) dbus.ObjectPath {
+	path := knownNetworkPath(ssid, typ)
+	s.AddInterface(path, iwdKnownNetworkIface, map[string]interface{}{
+		"Name":              ssid,
+		"Type":              typ,
+		"Hidden":            false,
+		"AutoConnect":       true,
+		"LastConnectedTime": "1970-01-01T00:00:00Z",
+	})
+	for _, network := range s.networks(func(props map[string]dbus.Variant) bool {
+		return props["Name"].Value() == ssid && props["Type"].Value() == typ
+	}) {
+		s.SetProperty(network, iwdNetworkIface, "KnownNetwork", path)
+	}
+	return path
+}
+
+// SetStationState changes the State property of station.
+func (s *Server) SetStationState(station dbus.ObjectPath, state string) {
+	s.SetProperty(station, iwdStationIface, "State", state)
+}
+
+// CallAgent calls member of the net.connman.iwd.Agent
+// interface on the agent registered with the
+// AgentManager and returns the reply.
+func (s *Server) CallAgent(member string, args ...interface{}) ([]interface{}, error) {
+	s.mu.Lock()
+	agent := s.agent
+	s.mu.Unlock()
+	if agent == nil {
+		return nil, Error("NoAgent", "no agent registered")
+	}
+	if b, ok := s.client(agent.sender); ok {
+		return b.callExport(agent.path, iwdAgentIface, member, args)
+	}
+	call := s.conn.Object(agent.sender, agent.path).Call(iwdAgentIface+"."+member, 0, args...)
+	return call.Body, call.Err
+}
+
+func knownNetworkPath(ssid, typ string) dbus.ObjectPath {
+	return dbus.ObjectPath(fmt.Sprintf("%s/%s_%s", iwdObjPath, hex.EncodeToString([]byte(ssid)), typ))
+}
+
+// networks returns the sorted paths of the networks whose
+// properties match.
+func (s *Server) networks(match func(map[string]dbus.Variant) bool) []dbus.ObjectPath {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var paths []dbus.ObjectPath
+	for p, ifaces := range s.objects {
+		if props, ok := ifaces[iwdNetworkIface]; ok && match(props) {
+			paths = append(paths, p)
+		}
+	}
+	sort.Slice(paths, func(a, b int) bool { return paths[a] < paths[b] })
+	return paths
+}
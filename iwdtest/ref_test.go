@@ -0,0 +1,81 @@
+package iwdtest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	iwd "github.com/shtirlic/go-iwd"
+	"github.com/shtirlic/go-iwd/iwdtest"
+)
+
+func TestRefResolvesLive(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *iwdtest.Server, i *iwd.Iwd) {
+		station := srv.AddStation(srv.AddAdapter("phy0"), "wlan0", "02:00:00:00:00:01")
+		network := srv.AddNetwork(station, "Cafe", "psk", -6000)
+		networks, err := i.Networks()
+		if err != nil {
+			t.Fatalf("Networks() = %v", err)
+		}
+		srv.SetProperty(station, "net.connman.iwd.Device", "Name", "wlan1")
+		device, err := networks[0].Device.Resolve()
+		if err != nil {
+			t.Fatalf("Device.Resolve() = %v", err)
+		}
+		if device.Name != "wlan1" {
+			t.Errorf("Device.Resolve().Name = %q, want the current name wlan1", device.Name)
+		}
+
+		srv.RemoveObject(station)
+		if _, err := networks[0].Device.Resolve(); !errors.Is(err, iwd.ErrUnknownObject) {
+			t.Errorf("Device.Resolve() of a removed device = %v, want ErrUnknownObject", err)
+		}
+		if networks[0].Path != network {
+			t.Errorf("Networks()[0].Path = %s, want %s", networks[0].Path, network)
+		}
+	})
+}
+
+func TestRefResolvesSnapshot(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *iwdtest.Server, i *iwd.Iwd) {
+		station := srv.AddStation(srv.AddAdapter("phy0"), "wlan0", "02:00:00:00:00:01")
+		srv.AddNetwork(station, "Cafe", "psk", -6000)
+		srv.AddNetwork(station, "Library", "open", -7000)
+		m, err := iwd.NewManager(context.Background(), i)
+		if err != nil {
+			t.Fatalf("NewManager() = %v", err)
+		}
+		defer m.Close()
+		snapshot := m.Snapshot()
+		srv.SetProperty(station, "net.connman.iwd.Device", "Name", "wlan1")
+
+		networks, err := snapshot.Networks()
+		if err != nil {
+			t.Fatalf("Networks() = %v", err)
+		}
+		if len(networks) != 2 {
+			t.Fatalf("Networks() = %+v, want two networks", networks)
+		}
+		first, err := networks[0].Device.Resolve()
+		if err != nil {
+			t.Fatalf("Device.Resolve() = %v", err)
+		}
+		second, err := networks[1].Device.Resolve()
+		if err != nil {
+			t.Fatalf("Device.Resolve() = %v", err)
+		}
+		if first != second {
+			t.Error("references to the same device resolve to different instances")
+		}
+		if first.Name != "wlan0" {
+			t.Errorf("Device.Resolve().Name = %q, want wlan0 as in the snapshot", first.Name)
+		}
+		devices, err := snapshot.Devices()
+		if err != nil {
+			t.Fatalf("Devices() = %v", err)
+		}
+		if len(devices) != 1 || devices[0] != first {
+			t.Errorf("Devices() = %+v, want the resolved device", devices)
+		}
+	})
+}
@@ -0,0 +1,140 @@
+package iwdtest
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+	iwd "github.com/shtirlic/go-iwd"
+)
+
+// replay serves the calls of a recording in place of the
+// fake objects.
+type replay struct {
+	calls []replayCall
+}
+
+type replayCall struct {
+	key     []byte
+	message iwd.RecordedMessage
+	signals []iwd.RecordedMessage
+	served  bool
+}
+
+// NewReplayServer starts a Server that replays a recording
+// made with iwd.Iwd.Record, see Server.Replay.
+//
+//	f, err := os.Open("testdata/roam.jsonl")
+//	...
+//	recording, err := iwd.ReadRecording(f)
+//	...
+//	srv, err := iwdtest.NewReplayServer(recording)
+func NewReplayServer(recording []iwd.RecordedMessage) (*Server, error) {
+	s, err := NewServer()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Replay(recording); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Replay answers method calls from recording instead of
+// the fake objects, replacing any recording replayed
+// before.  Each call is answered with the reply or error of
+// the first recorded call not yet served that has the same
+// path, method and arguments.  A call is recorded once its
+// reply arrives, so the signals recorded since the
+// previous call, usually emitted by iwd while handling it,
+// are emitted just before the reply; signals recorded
+// after the last call are emitted with it.  Calls missing
+// from the recording fail with
+// org.freedesktop.DBus.Error.Failed.  Calls iwd made on
+// agents are not replayed.  Handlers installed with Handle
+// take precedence.
+func (s *Server) Replay(recording []iwd.RecordedMessage) error {
+	r := &replay{}
+	var signals []iwd.RecordedMessage
+	for _, m := range recording {
+		if m.Agent {
+			continue
+		}
+		if m.Signal {
+			signals = append(signals, m)
+			continue
+		}
+		key, err := replayKey(m.Path, m.Name, m.Args)
+		if err != nil {
+			return err
+		}
+		r.calls = append(r.calls, replayCall{key: key, message: m, signals: signals})
+		signals = nil
+	}
+	if len(r.calls) > 0 {
+		last := &r.calls[len(r.calls)-1]
+		last.signals = append(last.signals, signals...)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replay = r
+	return nil
+}
+
+// Unreplayed returns the recorded calls that have not been
+// served, e.g. to check that code under test made every
+// call of the recording.
+func (s *Server) Unreplayed() []iwd.RecordedMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.replay == nil {
+		return nil
+	}
+	var calls []iwd.RecordedMessage
+	for _, c := range s.replay.calls {
+		if !c.served {
+			calls = append(calls, c.message)
+		}
+	}
+	return calls
+}
+
+// replayKey encodes a call the way recordings do, so that
+// calls compare equal whatever Go types their arguments
+// were decoded into.
+func replayKey(path dbus.ObjectPath, method string, args []interface{}) ([]byte, error) {
+	return iwd.RecordedMessage{Path: path, Name: method, Args: args}.MarshalJSON()
+}
+
+// serveReplay answers c from the recording.  It must be
+// called with s.mu held and releases it.
+func (s *Server) serveReplay(c Call) ([]interface{}, error) {
+	key, err := replayKey(c.Path, c.Method, c.Args)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, dbus.MakeFailedError(fmt.Errorf("iwdtest: cannot encode call %s on %s: %w", c.Method, c.Path, err))
+	}
+	var served *replayCall
+	for n := range s.replay.calls {
+		rc := &s.replay.calls[n]
+		if !rc.served && bytes.Equal(rc.key, key) {
+			rc.served = true
+			served = rc
+			break
+		}
+	}
+	s.mu.Unlock()
+	if served == nil {
+		return nil, dbus.MakeFailedError(fmt.Errorf("iwdtest: no recorded call %s on %s", c.Method, c.Path))
+	}
+	for _, sig := range served.signals {
+		if err := s.Emit(sig.Path, sig.Name, sig.Args...); err != nil {
+			return nil, err
+		}
+	}
+	if served.message.Error != nil {
+		return nil, served.message.Error
+	}
+	return served.message.Reply, nil
+}
@@ -0,0 +1,288 @@
+package iwdtest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	iwd "github.com/shtirlic/go-iwd"
+	"github.com/shtirlic/go-iwd/iwdtest"
+)
+
+const (
+	callRegisterAgent            = "net.connman.iwd.AgentManager.RegisterAgent"
+	callRegisterSignalLevelAgent = "net.connman.iwd.Station.RegisterSignalLevelAgent"
+	callGetManagedObjects        = "org.freedesktop.DBus.ObjectManager.GetManagedObjects"
+)
+
+func populate(srv *iwdtest.Server) {
+	station := srv.AddStation(srv.AddAdapter("phy0"), "wlan0", "02:00:00:00:00:01")
+	srv.AddNetwork(station, "Cafe", "psk", -6000)
+}
+
+func TestRestart(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *iwdtest.Server, i *iwd.Iwd) {
+		populate(srv)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		events, err := i.SubscribeDaemonEvents(ctx)
+		if err != nil {
+			t.Fatalf("SubscribeDaemonEvents() = %v", err)
+		}
+		changed, err := i.SubscribePropertiesChanged(ctx)
+		if err != nil {
+			t.Fatalf("SubscribePropertiesChanged() = %v", err)
+		}
+		m, err := iwd.NewManager(ctx, i)
+		if err != nil {
+			t.Fatalf("NewManager() = %v", err)
+		}
+		defer m.Close()
+		agent := &testAgent{}
+		if err := i.RegisterAgent("/test/agent", agent); err != nil {
+			t.Fatalf("RegisterAgent() = %v", err)
+		}
+		stations, err := i.Stations()
+		if err != nil {
+			t.Fatalf("Stations() = %v", err)
+		}
+		station := stations[0]
+		daemon, err := i.Daemon()
+		if err != nil {
+			t.Fatalf("Daemon() = %v", err)
+		}
+		level, err := station.RegisterSignalLevelAgent("/test/level", []int16{-60})
+		if err != nil {
+			t.Fatalf("RegisterSignalLevelAgent() = %v", err)
+		}
+		if !station.Valid() {
+			t.Fatal("Valid() = false before the restart")
+		}
+
+		if err := srv.Stop(); err != nil {
+			t.Fatalf("Stop() = %v", err)
+		}
+		if event := receive(t, events.Events); event.Running || event.Owner != "" {
+			t.Errorf("DaemonEvent = %+v, want iwd gone", event)
+		}
+		if station.Valid() {
+			t.Error("Valid() = true after iwd exited")
+		}
+		if err := station.Scan(); !errors.Is(err, iwd.ErrStale) {
+			t.Errorf("Scan() on a stale station = %v, want ErrStale", err)
+		}
+		select {
+		case _, open := <-level.Changes:
+			t.Errorf("signal level agent delivered or closed (open %v) after iwd exited", open)
+		default:
+		}
+		eventually(t, "the manager to empty its tree", func() bool {
+			return len(m.Snapshot().Paths("net.connman.iwd.Station")) == 0
+		})
+
+		srv.ResetCalls()
+		if err := srv.Start(); err != nil {
+			t.Fatalf("Start() = %v", err)
+		}
+		populate(srv)
+		if event := receive(t, events.Events); !event.Running || event.Owner == "" || event.Err != nil {
+			t.Errorf("DaemonEvent = %+v, want iwd running", event)
+		}
+		eventually(t, "the agent to be registered again", func() bool {
+			return len(srv.CallsTo(callRegisterAgent)) > 0
+		})
+		if calls := srv.CallsTo(callRegisterAgent); len(calls) != 1 || calls[0].Args[0] != dbus.ObjectPath("/test/agent") {
+			t.Errorf("RegisterAgent calls after the restart = %+v, want one for /test/agent", calls)
+		}
+		eventually(t, "the signal level agent to be registered again", func() bool {
+			return len(srv.CallsTo(callRegisterSignalLevelAgent)) > 0
+		})
+		if calls := srv.CallsTo(callRegisterSignalLevelAgent); len(calls) != 1 || calls[0].Path != station.Path ||
+			calls[0].Args[0] != dbus.ObjectPath("/test/level") {
+			t.Errorf("RegisterSignalLevelAgent calls after the restart = %+v, want one for /test/level on %s", calls, station.Path)
+		}
+		eventually(t, "the manager to reload its tree", func() bool {
+			return len(m.Snapshot().Paths("net.connman.iwd.Station")) == 1
+		})
+		if err := m.Err(); err != nil {
+			t.Errorf("Manager.Err() = %v after a reload", err)
+		}
+
+		if err := station.Refresh(); err != nil {
+			t.Fatalf("Refresh() = %v", err)
+		}
+		if !station.Valid() {
+			t.Error("Valid() = false after Refresh")
+		}
+		if err := daemon.Refresh(); err != nil {
+			t.Fatalf("Daemon.Refresh() = %v", err)
+		}
+		if _, err := daemon.GetInfo(); err != nil {
+			t.Errorf("GetInfo() after Refresh = %v", err)
+		}
+		for len(changed.Events) > 0 {
+			<-changed.Events
+		}
+		if err := station.Scan(); err != nil {
+			t.Fatalf("Scan() = %v", err)
+		}
+		if change := receive(t, changed.Events); change.Path != station.Path {
+			t.Errorf("PropertiesChanged after the restart = %+v, want one for %s", change, station.Path)
+		}
+		networks, err := station.GetOrderedNetworks()
+		if err != nil {
+			t.Fatalf("GetOrderedNetworks() = %v", err)
+		}
+		if err := networks[0].Connect(); err != nil {
+			t.Fatalf("Connect() = %v", err)
+		}
+		if got := agent.Requests(); len(got) != 1 {
+			t.Errorf("agent was asked for %v, want one passphrase", got)
+		}
+		if err := level.Close(); err != nil {
+			t.Errorf("closing the signal level agent after the restart = %v", err)
+		}
+		if _, open := <-level.Changes; open {
+			t.Error("signal level agent still open after Close")
+		}
+	})
+}
+
+func TestRestartRetry(t *testing.T) {
+	srv := iwdtest.NewMemoryServer()
+	defer srv.Close()
+	i := iwd.NewIwdWithBackend(srv.Backend())
+	defer i.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := i.SubscribeDaemonEvents(ctx)
+	if err != nil {
+		t.Fatalf("SubscribeDaemonEvents() = %v", err)
+	}
+	m, err := iwd.NewManager(ctx, i)
+	if err != nil {
+		t.Fatalf("NewManager() = %v", err)
+	}
+	defer m.Close()
+	if err := i.RegisterAgent("/test/agent", &testAgent{}); err != nil {
+		t.Fatalf("RegisterAgent() = %v", err)
+	}
+
+	// iwd is not ready for the agent right after it
+	// appears, then settles.
+	srv.Stop()
+	receive(t, events.Events)
+	failures := make(chan struct{}, 2)
+	failures <- struct{}{}
+	failures <- struct{}{}
+	srv.Handle(callRegisterAgent, func(iwdtest.Call) ([]interface{}, error) {
+		select {
+		case <-failures:
+			return nil, iwdtest.Error("Failed", "not ready")
+		default:
+			return nil, nil
+		}
+	})
+	srv.ResetCalls()
+	srv.Start()
+	if event := receive(t, events.Events); !event.Running || event.Err != nil {
+		t.Errorf("DaemonEvent = %+v, want iwd running without error", event)
+	}
+	eventually(t, "the agent to be registered again", func() bool {
+		return len(srv.CallsTo(callRegisterAgent)) == 3
+	})
+
+	// iwd keeps failing.
+	srv.Stop()
+	receive(t, events.Events)
+	srv.Handle(callRegisterAgent, func(iwdtest.Call) ([]interface{}, error) {
+		return nil, iwdtest.Error("Failed", "broken")
+	})
+	srv.Handle(callGetManagedObjects, func(iwdtest.Call) ([]interface{}, error) {
+		return nil, iwdtest.Error("Failed", "broken")
+	})
+	srv.Start()
+	if event := receive(t, events.Events); !event.Running || event.Err != nil {
+		t.Errorf("DaemonEvent = %+v, want iwd running", event)
+	}
+	if event := receive(t, events.Events); !event.Running || !errors.Is(event.Err, iwd.ErrFailed) {
+		t.Errorf("second DaemonEvent = %+v, want the ErrFailed of the agent", event)
+	}
+	if len(events.Events) != 0 {
+		t.Errorf("unexpected DaemonEvent %+v", <-events.Events)
+	}
+	eventually(t, "the manager to give up reloading", func() bool {
+		return errors.Is(m.Err(), iwd.ErrFailed)
+	})
+	if err := m.Close(); err != nil {
+		t.Errorf("Close() = %v", err)
+	}
+	if err := m.Close(); err != nil {
+		t.Errorf("second Close() = %v", err)
+	}
+}
+
+func TestRestartEarlySignals(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *iwdtest.Server, i *iwd.Iwd) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		events, err := i.SubscribeDaemonEvents(ctx)
+		if err != nil {
+			t.Fatalf("SubscribeDaemonEvents() = %v", err)
+		}
+		changed, err := i.SubscribePropertiesChanged(ctx)
+		if err != nil {
+			t.Fatalf("SubscribePropertiesChanged() = %v", err)
+		}
+		// The first signals of a restarted iwd may reach the
+		// Iwd before it learns about the new owner.
+		for n := 0; n < 10; n++ {
+			if err := srv.Stop(); err != nil {
+				t.Fatalf("Stop() = %v", err)
+			}
+			receive(t, events.Events)
+			if err := srv.Start(); err != nil {
+				t.Fatalf("Start() = %v", err)
+			}
+			station := srv.AddStation(srv.AddAdapter("phy0"), "wlan0", "02:00:00:00:00:01")
+			srv.SetStationState(station, "connecting")
+			if change := receive(t, changed.Events); change.Path != station || change.Changed["State"].Value() != "connecting" {
+				t.Fatalf("PropertiesChanged after restart %d = %+v, want State connecting on %s", n, change, station)
+			}
+			if event := receive(t, events.Events); !event.Running {
+				t.Fatalf("DaemonEvent = %+v, want iwd running", event)
+			}
+		}
+	})
+}
+
+func TestSignalLevelAgentCloseStale(t *testing.T) {
+	srv := iwdtest.NewMemoryServer()
+	defer srv.Close()
+	i := iwd.NewIwdWithBackend(srv.Backend())
+	defer i.Close()
+	populate(srv)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := i.SubscribeDaemonEvents(ctx)
+	if err != nil {
+		t.Fatalf("SubscribeDaemonEvents() = %v", err)
+	}
+	stations, err := i.Stations()
+	if err != nil {
+		t.Fatalf("Stations() = %v", err)
+	}
+	level, err := stations[0].RegisterSignalLevelAgent("/test/level", []int16{-60})
+	if err != nil {
+		t.Fatalf("RegisterSignalLevelAgent() = %v", err)
+	}
+	srv.Stop()
+	receive(t, events.Events)
+	if err := level.Close(); !errors.Is(err, iwd.ErrStale) {
+		t.Errorf("Close() while iwd is gone = %v, want ErrStale", err)
+	}
+	if _, open := <-level.Changes; open {
+		t.Error("signal level agent still open after Close")
+	}
+}
@@ -0,0 +1,406 @@
+// Package iwdtest provides a scriptable in-process fake of
+// the iwd D-Bus service for testing code built on go-iwd
+// without Wi-Fi hardware.
+//
+// A Server made with NewServer starts a private
+// dbus-daemon, which must be installed, and owns
+// net.connman.iwd on it.  Connections returned by
+// Server.Conn can be passed to iwd.NewIwdWithConn.
+//
+//	srv, err := iwdtest.NewServer()
+//	...
+//	defer srv.Close()
+//	adapter := srv.AddAdapter("phy0")
+//	station := srv.AddStation(adapter, "wlan0", "02:00:00:00:00:01")
+//	srv.AddNetwork(station, "Cafe", "psk", -6000)
+//	conn, err := srv.Conn()
+//	...
+//	i := iwd.NewIwdWithConn(conn)
+//
+// NewMemoryServer runs the same fake without a bus; it is
+// reached through in-memory backends instead.
+//
+// Traffic recorded from a real iwd with iwd.Iwd.Record can
+// be served back with NewReplayServer.
+package iwdtest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	iwdService = "net.connman.iwd"
+	iwdObjPath = "/net/connman/iwd"
+
+	dbusPropertiesIface    = "org.freedesktop.DBus.Properties"
+	dbusObjectManagerIface = "org.freedesktop.DBus.ObjectManager"
+
+	busConfig = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:dir=%s</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`
+)
+
+// Call is a method call received by the Server.
+type Call struct {
+	Sender string          // Unique bus name of the caller
+	Path   dbus.ObjectPath // Object the method was called on
+	Method string          // Interface and member, e.g. net.connman.iwd.Station.Scan
+	Args   []interface{}   // Arguments as decoded from the message
+}
+
+// MethodFunc implements a method of the fake service.  The
+// returned values are sent as the reply; a returned error
+// is sent as a D-Bus error, see Error.
+type MethodFunc func(call Call) ([]interface{}, error)
+
+// Server is a fake iwd service on a private bus.  All
+// methods are safe for concurrent use.
+type Server struct {
+	address string
+	dir     string
+	cmd     *exec.Cmd
+	conn    *dbus.Conn
+
+	mu       sync.Mutex
+	objects  map[dbus.ObjectPath]map[string]map[string]dbus.Variant
+	signals  map[dbus.ObjectPath]int16
+	handlers map[string]MethodFunc
+	calls    []Call
+	agent    *registeredAgent
+	clients  map[string]*memoryBackend
+	nclients int
+	replay   *replay
+	stopped  bool
+	starts   int
+	phys     int
+	devices  int
+}
+
+type registeredAgent struct {
+	sender string
+	path   dbus.ObjectPath
+}
+
+// Error returns a D-Bus error in the net.connman.iwd
+// namespace, e.g. Error("NotFound", "no such network"),
+// for use in MethodFuncs.
+func Error(name, message string) error {
+	return dbus.NewError(iwdService+"."+name, []interface{}{message})
+}
+
+// NewServer starts a private dbus-daemon and registers the
+// fake service on it.  The service starts out with only the
+// daemon object at /net/connman/iwd.
+func NewServer() (*Server, error) {
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "iwdtest")
+	if err != nil {
+		return nil, err
+	}
+	s := newServer()
+	s.dir = dir
+	config := filepath.Join(dir, "bus.conf")
+	if err := os.WriteFile(config, []byte(fmt.Sprintf(busConfig, dir)), 0o600); err != nil {
+		s.Close()
+		return nil, err
+	}
+	s.cmd = exec.Command(daemon, "--config-file="+config, "--print-address=1", "--nofork")
+	stdout, err := s.cmd.StdoutPipe()
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	if err := s.cmd.Start(); err != nil {
+		s.cmd = nil
+		s.Close()
+		return nil, err
+	}
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		s.Close()
+		return nil, fmt.Errorf("iwdtest: reading dbus-daemon address: %w", err)
+	}
+	s.address = strings.TrimSpace(address)
+	if s.conn, err = dbus.Connect(s.address, dbus.WithHandler(s)); err != nil {
+		s.Close()
+		return nil, err
+	}
+	reply, err := s.conn.RequestName(iwdService, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		s.Close()
+		return nil, errors.New("iwdtest: " + iwdService + " already taken")
+	}
+	return s, nil
+}
+
+// newServer returns a Server with only the daemon object
+// and no bus.
+func newServer() *Server {
+	s := &Server{
+		handlers: make(map[string]MethodFunc),
+		clients:  make(map[string]*memoryBackend),
+	}
+	s.reset()
+	return s
+}
+
+// reset drops all objects but the daemon object, as if iwd
+// had just started.
+func (s *Server) reset() {
+	s.mu.Lock()
+	s.objects = make(map[dbus.ObjectPath]map[string]map[string]dbus.Variant)
+	s.signals = make(map[dbus.ObjectPath]int16)
+	s.agent = nil
+	s.phys = 0
+	s.devices = 0
+	s.mu.Unlock()
+	s.AddObject(iwdObjPath, map[string]map[string]interface{}{
+		iwdService + ".Daemon":       {},
+		iwdService + ".AgentManager": {},
+	})
+}
+
+// Stop simulates iwd exiting: the service leaves the bus,
+// forgets all objects and the registered agent, and calls
+// fail until Start is called.
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+	if s.conn != nil {
+		if _, err := s.conn.ReleaseName(iwdService); err != nil {
+			return err
+		}
+	}
+	s.ownerChanged("")
+	return nil
+}
+
+// Start simulates iwd starting again after Stop.  The
+// service comes back with only the daemon object; paths
+// are numbered from the start again, as a restarted iwd
+// does.
+func (s *Server) Start() error {
+	s.reset()
+	s.mu.Lock()
+	s.stopped = false
+	s.starts++
+	s.mu.Unlock()
+	if s.conn != nil {
+		reply, err := s.conn.RequestName(iwdService, dbus.NameFlagDoNotQueue)
+		if err != nil {
+			return err
+		}
+		if reply != dbus.RequestNameReplyPrimaryOwner {
+			return errors.New("iwdtest: " + iwdService + " already taken")
+		}
+	}
+	s.ownerChanged(s.owner())
+	return nil
+}
+
+// owner returns the unique name the service is seen under
+// while it runs.  Without a bus every Start gets a new one,
+// as a restarted iwd does.
+func (s *Server) owner() string {
+	if s.conn != nil {
+		return s.conn.Names()[0]
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fmt.Sprintf(":iwdtest.%d", s.starts)
+}
+
+// Address returns the address of the private bus, or ""
+// for a Server made with NewMemoryServer.
+func (s *Server) Address() string {
+	return s.address
+}
+
+// Conn opens a new client connection to the private bus.
+func (s *Server) Conn() (*dbus.Conn, error) {
+	if s.address == "" {
+		return nil, errors.New("iwdtest: server has no bus")
+	}
+	return dbus.Connect(s.address)
+}
+
+// Close stops the service and the private bus.
+func (s *Server) Close() error {
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	if s.cmd != nil {
+		s.cmd.Process.Kill()
+		s.cmd.Wait()
+	}
+	return os.RemoveAll(s.dir)
+}
+
+// Handle replaces the implementation of method, given as
+// interface and member, e.g. net.connman.iwd.Station.Scan.
+// Calls are still recorded.
+func (s *Server) Handle(method string, fn MethodFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[method] = fn
+}
+
+// Calls returns every method call received so far.
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// CallsTo returns the calls received for method, given as
+// interface and member.
+func (s *Server) CallsTo(method string) []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var calls []Call
+	for _, c := range s.calls {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// ResetCalls forgets the calls recorded so far.
+func (s *Server) ResetCalls() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = nil
+}
+
+// Emit sends a signal from the service, on the bus and to
+// every Backend.
+func (s *Server) Emit(path dbus.ObjectPath, name string, values ...interface{}) error {
+	if s.conn != nil {
+		if err := s.conn.Emit(path, name, values...); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	clients := make([]*memoryBackend, 0, len(s.clients))
+	for _, b := range s.clients {
+		clients = append(clients, b)
+	}
+	s.mu.Unlock()
+	for _, b := range clients {
+		if err := b.deliver(path, name, values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LookupObject implements dbus.Handler so that every call
+// to the service goes through dispatch.
+func (s *Server) LookupObject(path dbus.ObjectPath) (dbus.ServerObject, bool) {
+	if !s.exists(path) {
+		return nil, false
+	}
+	return serverObject{s, path}, true
+}
+
+// exists reports whether calls to path are served.
+func (s *Server) exists(path dbus.ObjectPath) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.objects[path]
+	return ok || path == "/" || s.replay != nil
+}
+
+type serverObject struct {
+	s    *Server
+	path dbus.ObjectPath
+}
+
+func (o serverObject) LookupInterface(name string) (dbus.Interface, bool) {
+	return serverInterface{o.s, o.path, name}, true
+}
+
+type serverInterface struct {
+	s     *Server
+	path  dbus.ObjectPath
+	iface string
+}
+
+func (i serverInterface) LookupMethod(name string) (dbus.Method, bool) {
+	return &serverMethod{s: i.s, path: i.path, method: i.iface + "." + name}, true
+}
+
+// serverMethod passes the raw message body and the sender
+// to dispatch instead of decoding into typed arguments.
+type serverMethod struct {
+	s      *Server
+	path   dbus.ObjectPath
+	method string
+}
+
+func (m *serverMethod) DecodeArguments(_ *dbus.Conn, sender string, _ *dbus.Message,
+	args []interface{}) ([]interface{}, error) {
+
+	return append([]interface{}{sender}, args...), nil
+}
+
+func (m *serverMethod) Call(args ...interface{}) ([]interface{}, error) {
+	sender, _ := args[0].(string)
+	return m.s.dispatch(Call{Sender: sender, Path: m.path, Method: m.method, Args: args[1:]})
+}
+
+func (m *serverMethod) NumArguments() int                      { return 0 }
+func (m *serverMethod) NumReturns() int                        { return 0 }
+func (m *serverMethod) ArgumentValue(position int) interface{} { return nil }
+func (m *serverMethod) ReturnValue(position int) interface{}   { return nil }
+
+func (s *Server) dispatch(c Call) ([]interface{}, error) {
+	s.mu.Lock()
+	s.calls = append(s.calls, c)
+	fn, ok := s.handlers[c.Method]
+	if !ok && s.replay != nil {
+		return s.serveReplay(c)
+	}
+	s.mu.Unlock()
+	if ok {
+		return fn(c)
+	}
+	if fn, ok := builtinMethods[c.Method]; ok {
+		return fn(s, c)
+	}
+	iface := c.Method[:strings.LastIndex(c.Method, ".")]
+	if !s.HasInterface(c.Path, iface) {
+		return nil, dbus.MakeUnknownInterfaceError(iface)
+	}
+	// Unscripted methods of known interfaces succeed
+	// without a reply value.
+	return nil, nil
+}
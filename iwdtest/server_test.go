@@ -0,0 +1,89 @@
+package iwdtest_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	iwd "github.com/shtirlic/go-iwd"
+	"github.com/shtirlic/go-iwd/iwdtest"
+)
+
+const (
+	callStationScan                 = "net.connman.iwd.Station.Scan"
+	callStationConnectHiddenNetwork = "net.connman.iwd.Station.ConnectHiddenNetwork"
+)
+
+func TestServerCalls(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *iwdtest.Server, i *iwd.Iwd) {
+		station := srv.AddStation(srv.AddAdapter("phy0"), "wlan0", "02:00:00:00:00:01")
+		s, err := iwd.NewStation(station, i)
+		if err != nil {
+			t.Fatalf("NewStation() = %v", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		changed, err := i.SubscribeStationChanged(ctx)
+		if err != nil {
+			t.Fatalf("SubscribeStationChanged() = %v", err)
+		}
+		var scans atomic.Int32
+		srv.Handle(callStationScan, func(iwdtest.Call) ([]interface{}, error) {
+			scans.Add(1)
+			return nil, nil
+		})
+
+		srv.ResetCalls()
+		if calls := srv.Calls(); len(calls) != 0 {
+			t.Errorf("Calls() after ResetCalls = %+v, want none", calls)
+		}
+		if err := s.Scan(); err != nil {
+			t.Fatalf("Scan() = %v", err)
+		}
+		if err := s.ConnectHiddenNetwork("Hidden"); err != nil {
+			t.Fatalf("ConnectHiddenNetwork() = %v", err)
+		}
+		if n := scans.Load(); n != 1 {
+			t.Errorf("Scan() called the handler %d times, want once", n)
+		}
+		// The handler replaces the builtin Scan, which sets
+		// Scanning.
+		select {
+		case change := <-changed.Events:
+			t.Errorf("StationChanged %+v from a replaced Scan", change)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		calls := srv.Calls()
+		if len(calls) != 2 || calls[0].Method != callStationScan || calls[1].Method != callStationConnectHiddenNetwork {
+			t.Fatalf("Calls() = %+v, want Scan and ConnectHiddenNetwork", calls)
+		}
+		hidden := srv.CallsTo(callStationConnectHiddenNetwork)
+		if len(hidden) != 1 || hidden[0].Path != station || !reflect.DeepEqual(hidden[0].Args, []interface{}{"Hidden"}) {
+			t.Errorf("CallsTo(ConnectHiddenNetwork) = %+v, want one call with Hidden on %s", hidden, station)
+		}
+		if calls := srv.CallsTo("net.connman.iwd.Station.Disconnect"); len(calls) != 0 {
+			t.Errorf("CallsTo(Disconnect) = %+v, want none", calls)
+		}
+	})
+}
+
+func TestServerUnknownInterface(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *iwdtest.Server, i *iwd.Iwd) {
+		station := srv.AddStation(srv.AddAdapter("phy0"), "wlan0", "02:00:00:00:00:01")
+		s, err := iwd.NewStation(station, i)
+		if err != nil {
+			t.Fatalf("NewStation() = %v", err)
+		}
+		srv.RemoveInterface(station, "net.connman.iwd.Station")
+		err = s.ConnectHiddenNetwork("Hidden")
+		var dbusErr dbus.Error
+		if !errors.As(err, &dbusErr) || dbusErr.Name != "org.freedesktop.DBus.Error.UnknownInterface" {
+			t.Errorf("ConnectHiddenNetwork() on a removed station = %v, want UnknownInterface", err)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package iwdtest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	iwd "github.com/shtirlic/go-iwd"
+	"github.com/shtirlic/go-iwd/iwdtest"
+)
+
+func TestSubscriptionTyped(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *iwdtest.Server, i *iwd.Iwd) {
+		adapter := srv.AddAdapter("phy0")
+		station := srv.AddStation(adapter, "wlan0", "02:00:00:00:00:01")
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		stations, err := i.SubscribeStationChanged(ctx)
+		if err != nil {
+			t.Fatalf("SubscribeStationChanged() = %v", err)
+		}
+		adapters, err := i.SubscribeAdapterChanged(ctx)
+		if err != nil {
+			t.Fatalf("SubscribeAdapterChanged() = %v", err)
+		}
+
+		srv.SetProperty(adapter, "net.connman.iwd.Adapter", "Powered", false)
+		srv.SetStationState(station, "connecting")
+		change := receive(t, stations.Events)
+		if change.Path != station || change.State == nil || *change.State != iwd.ConnectingState {
+			t.Errorf("StationChanged = %+v, want State connecting on %s", change, station)
+		}
+		if change.Scanning != nil || change.ConnectedNetwork != nil {
+			t.Errorf("StationChanged = %+v, want only State set", change)
+		}
+		powered := receive(t, adapters.Events)
+		if powered.Path != adapter || powered.Powered == nil || *powered.Powered {
+			t.Errorf("AdapterChanged = %+v, want Powered false on %s", powered, adapter)
+		}
+		select {
+		case change := <-stations.Events:
+			t.Errorf("StationChanged %+v for a change of the adapter", change)
+		case change := <-adapters.Events:
+			t.Errorf("AdapterChanged %+v for a change of the station", change)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+}
+
+func TestSubscriptionDrop(t *testing.T) {
+	srv := iwdtest.NewMemoryServer()
+	defer srv.Close()
+	i := iwd.NewIwdWithBackend(srv.Backend())
+	defer i.Close()
+	station := srv.AddStation(srv.AddAdapter("phy0"), "wlan0", "02:00:00:00:00:01")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	slow, err := i.SubscribePropertiesChanged(ctx)
+	if err != nil {
+		t.Fatalf("SubscribePropertiesChanged() = %v", err)
+	}
+	fast, err := i.SubscribeStationChanged(ctx)
+	if err != nil {
+		t.Fatalf("SubscribeStationChanged() = %v", err)
+	}
+
+	// Nobody reads slow, which must not hold up fast or
+	// the service.
+	const changes = 500
+	states := []string{"connecting", "connected"}
+	for n := 0; n < changes; n++ {
+		srv.SetStationState(station, states[n%2])
+		if change := receive(t, fast.Events); *change.State != iwd.ConnectionState(states[n%2]) {
+			t.Fatalf("StationChanged %d = %+v, want State %s", n, change, states[n%2])
+		}
+	}
+
+	kept := 0
+	for len(slow.Events) > 0 {
+		<-slow.Events
+		kept++
+	}
+	if kept == 0 || kept >= changes {
+		t.Errorf("slow subscription kept %d of %d changes, want some dropped", kept, changes)
+	}
+	// Signals queued before draining are delivered first.
+	srv.SetStationState(station, "disconnected")
+	for {
+		if change := receive(t, slow.Events); change.Changed["State"].Value() == "disconnected" {
+			break
+		}
+	}
+}
+
+func TestSubscriptionClose(t *testing.T) {
+	srv := iwdtest.NewMemoryServer()
+	defer srv.Close()
+	i := iwd.NewIwdWithBackend(srv.Backend())
+	defer i.Close()
+	station := srv.AddStation(srv.AddAdapter("phy0"), "wlan0", "02:00:00:00:00:01")
+	closed, err := i.SubscribePropertiesChanged(context.Background())
+	if err != nil {
+		t.Fatalf("SubscribePropertiesChanged() = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled, err := i.SubscribePropertiesChanged(ctx)
+	if err != nil {
+		t.Fatalf("SubscribePropertiesChanged() = %v", err)
+	}
+	if err := closed.Close(); err != nil {
+		t.Errorf("Close() = %v", err)
+	}
+	if err := closed.Close(); err != nil {
+		t.Errorf("second Close() = %v", err)
+	}
+	cancel()
+	srv.SetStationState(station, "connecting")
+	for _, s := range []*iwd.Subscription[iwd.PropertiesChanged]{closed, canceled} {
+		for {
+			change, open := <-s.Events
+			if !open {
+				break
+			}
+			// Delivered before the context was seen.
+			if s == closed {
+				t.Errorf("PropertiesChanged %+v after Close", change)
+			}
+		}
+	}
+}
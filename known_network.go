@@ -1,8 +1,9 @@
 package iwd
 
 import (
+	"context"
+
 	"github.com/godbus/dbus/v5"
-	"github.com/shtirlic/go-iwd/utils"
 )
 
 const (
@@ -17,23 +18,50 @@ type KnownNetwork struct {
 	LastConnectedTime string // [ro]
 	Name              string // [ro]
 	Type              string // [ro]
-	iwd               *Iwd
+	object
 }
 
 func NewKnownNetwork(p dbus.ObjectPath, i *Iwd) (*KnownNetwork, error) {
-	objects, err := utils.GetAllProperties(i.conn, iwdService, p, iwdKnownNetworkIface)
+	return NewKnownNetworkContext(context.Background(), p, i)
+}
+
+// NewKnownNetworkContext is the context-aware variant of NewKnownNetwork.
+func NewKnownNetworkContext(ctx context.Context, p dbus.ObjectPath, i *Iwd) (*KnownNetwork, error) {
+	return newKnownNetwork(ctx, p, i, liveSource{i})
+}
+
+func newKnownNetwork(ctx context.Context, p dbus.ObjectPath, i *Iwd, src propertySource) (*KnownNetwork, error) {
+	objects, err := src.properties(ctx, p, iwdKnownNetworkIface)
 	if err != nil {
 		return nil, err
 	}
-	return &KnownNetwork{
-		Path:              p,
-		AutoConnect:       objects["AutoConnect"].Value().(bool),
-		Hidden:            objects["Hidden"].Value().(bool),
-		LastConnectedTime: objects["LastConnectedTime"].Value().(string),
-		Name:              objects["Name"].Value().(string),
-		Type:              objects["Type"].Value().(string),
-		iwd:               i,
-	}, nil
+	knownNetwork := &KnownNetwork{
+		Path:   p,
+		object: i.object(),
+	}
+	if err := decodeProperties(objects, p, iwdKnownNetworkIface, knownNetwork); err != nil {
+		return nil, err
+	}
+	return knownNetwork, nil
+}
+
+// Reload the properties of the known network from iwd in
+// place.  The returned error matches ErrUnknownObject if
+// the known network has disappeared since.  Refresh writes
+// the fields, so the known network must not be used
+// concurrently.
+func (k *KnownNetwork) Refresh() error {
+	return k.RefreshContext(context.Background())
+}
+
+// RefreshContext is the context-aware variant of Refresh.
+func (k *KnownNetwork) RefreshContext(ctx context.Context) error {
+	fresh, err := NewKnownNetworkContext(ctx, k.Path, k.iwd)
+	if err != nil {
+		return err
+	}
+	*k = *fresh
+	return nil
 }
 
 // Removes the network from the 'known networks' list and
@@ -41,8 +69,27 @@ func NewKnownNetwork(p dbus.ObjectPath, i *Iwd) (*KnownNetwork, error) {
 // network is currently connected, then it is immediately
 // disconnected.
 func (k *KnownNetwork) Forget() error {
-	if _, err := k.iwd.CallServiceMethod(k.Path, callKnownNetworkForget); err != nil {
+	return k.ForgetContext(context.Background())
+}
+
+// ForgetContext is the context-aware variant of Forget.
+func (k *KnownNetwork) ForgetContext(ctx context.Context) error {
+	if _, err := k.callMethod(ctx, k.Path, callKnownNetworkForget); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Enable or disable automatic connection to the network.
+func (k *KnownNetwork) SetAutoConnect(autoConnect bool) error {
+	return k.SetAutoConnectContext(context.Background(), autoConnect)
+}
+
+// SetAutoConnectContext is the context-aware variant of SetAutoConnect.
+func (k *KnownNetwork) SetAutoConnectContext(ctx context.Context, autoConnect bool) error {
+	if err := k.setProperty(ctx, k.Path, iwdKnownNetworkIface, "AutoConnect", autoConnect); err != nil {
 		return err
 	}
+	k.AutoConnect = autoConnect
 	return nil
 }
@@ -0,0 +1,353 @@
+package iwd
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/shtirlic/go-iwd/utils"
+)
+
+// Manager mirrors iwd's object tree in memory.  The tree is
+// loaded once with GetManagedObjects and then kept current
+// from the PropertiesChanged, InterfacesAdded and
+// InterfacesRemoved signals, so reading it never talks to
+// iwd.  All methods are safe for concurrent use.
+type Manager struct {
+	iwd      *Iwd
+	mu       sync.RWMutex
+	objects  utils.DBusRetValues
+	handlers []*signalHandler
+	updated  chan struct{}
+	loaded   bool
+	pending  []func()
+	err      error // Error of the last failed reload
+	closed   bool
+}
+
+// Snapshot is an immutable copy of the object tree taken
+// by Manager.Snapshot.  Objects built from a snapshot are
+// consistent with each other, and every path is built only
+// once, so all references to it share the same instance.
+// References resolve against the snapshot, which they keep
+// alive.
+type Snapshot struct {
+	graph *graph
+	iwd   *Iwd
+}
+
+// NewManager loads the object tree of iwd and starts
+// tracking its changes.  ctx bounds the initial load only;
+// call Close to stop tracking.  The tree is emptied when
+// iwd vanishes from the bus and reloaded when it is back.
+func NewManager(ctx context.Context, i *Iwd) (*Manager, error) {
+	m := &Manager{
+		iwd:     i,
+		objects: utils.DBusRetValues{},
+		updated: make(chan struct{}, 1),
+	}
+	for _, s := range []struct {
+		iface, member string
+		handle        func(*dbus.Signal)
+	}{
+		{dbusPropertiesIface, signalPropertiesChangedMember, m.propertiesChanged},
+		{dbusObjectManagerIface, signalInterfacesAdded, m.interfacesAdded},
+		{dbusObjectManagerIface, signalInterfacesRemoved, m.interfacesRemoved},
+	} {
+		h, err := i.addSignalHandler("", s.iface, s.member, m.apply(s.handle))
+		if err != nil {
+			m.Close()
+			return nil, err
+		}
+		m.handlers = append(m.handlers, h)
+	}
+	h, err := i.addSignalHandler("", dbusService, signalNameOwnerChanged, m.daemonChanged)
+	if err != nil {
+		m.Close()
+		return nil, err
+	}
+	m.handlers = append(m.handlers, h)
+	if err := m.load(ctx); err != nil {
+		m.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// load replaces the tree with a fresh copy from iwd.
+func (m *Manager) load(ctx context.Context) error {
+	gen := m.iwd.gen.Load()
+	objects, err := m.iwd.getManagedObjects(ctx)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	if gen != m.iwd.gen.Load() {
+		// iwd vanished during the load, the tree will be
+		// loaded again once it is back.
+		m.mu.Unlock()
+		return nil
+	}
+	m.objects = objects
+	m.loaded = true
+	m.err = nil
+	// Signals that arrived while the tree was loading are
+	// replayed on top of it; applying a change twice is
+	// harmless.
+	for _, update := range m.pending {
+		update()
+	}
+	m.pending = nil
+	m.mu.Unlock()
+	m.notify()
+	return nil
+}
+
+// daemonChanged empties the tree when iwd vanishes and
+// reloads it in the background when iwd appears.  A reload
+// that keeps failing is reported by Err.
+func (m *Manager) daemonChanged(sig *dbus.Signal) {
+	event, ok := decodeDaemonEvent(sig)
+	if !ok || event.Err != nil {
+		// Errors re-registering agents do not affect the
+		// tree.
+		return
+	}
+	m.mu.Lock()
+	m.objects = utils.DBusRetValues{}
+	m.loaded = false
+	m.pending = nil
+	m.mu.Unlock()
+	m.notify()
+	if event.Running {
+		go m.reload(m.iwd.gen.Load())
+	}
+}
+
+// reload loads the tree of the iwd of generation gen.
+func (m *Manager) reload(gen uint64) {
+	err := m.iwd.retry(gen, func() error {
+		m.mu.RLock()
+		closed := m.closed
+		m.mu.RUnlock()
+		if closed {
+			return nil
+		}
+		return m.load(context.Background())
+	})
+	if errors.Is(err, errVanished) {
+		// Reloaded once iwd is back.
+		return
+	}
+	if err != nil {
+		m.mu.Lock()
+		m.err = err
+		m.mu.Unlock()
+		m.notify()
+	}
+}
+
+// Err returns the error that kept the Manager from
+// reloading the tree after iwd restarted, in which case
+// the tree stays empty until iwd restarts again.  It is
+// nil while the tree is loaded.
+func (m *Manager) Err() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.err
+}
+
+// apply returns a signal handler that runs update with the
+// tree locked, deferring it until the tree is loaded.
+func (m *Manager) apply(update func(*dbus.Signal)) func(*dbus.Signal) {
+	return func(sig *dbus.Signal) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if !m.loaded {
+			m.pending = append(m.pending, func() { update(sig) })
+			return
+		}
+		update(sig)
+		m.notify()
+	}
+}
+
+// Stop tracking changes of the object tree.  The tree
+// keeps its last state.  Closing a closed Manager does
+// nothing.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	handlers := m.handlers
+	m.handlers = nil
+	m.closed = true
+	m.mu.Unlock()
+	var err error
+	for _, h := range handlers {
+		if rerr := m.iwd.removeSignalHandler(h); rerr != nil && err == nil {
+			err = rerr
+		}
+	}
+	return err
+}
+
+// Updated returns a channel that receives a value after the
+// tree changed.  Changes are coalesced: a single value may
+// stand for several changes, so readers should take a new
+// Snapshot every time it fires.
+func (m *Manager) Updated() <-chan struct{} {
+	return m.updated
+}
+
+func (m *Manager) notify() {
+	select {
+	case m.updated <- struct{}{}:
+	default:
+	}
+}
+
+// Snapshot returns a copy of the current object tree.
+func (m *Manager) Snapshot() *Snapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	// Property maps are replaced rather than modified on
+	// change, so they can be shared with the snapshot.
+	objects := make(utils.DBusRetValues, len(m.objects))
+	for p, ifaces := range m.objects {
+		objects[p] = make(map[string]map[string]dbus.Variant, len(ifaces))
+		for iface, props := range ifaces {
+			objects[p][iface] = props
+		}
+	}
+	g := newGraph(objects)
+	g.snapshot = true
+	return &Snapshot{graph: g, iwd: m.iwd}
+}
+
+func (m *Manager) Adapters() ([]*Adapter, error) {
+	return m.Snapshot().Adapters()
+}
+
+func (m *Manager) Devices() ([]*Device, error) {
+	return m.Snapshot().Devices()
+}
+
+func (m *Manager) Stations() ([]*Station, error) {
+	return m.Snapshot().Stations()
+}
+
+func (m *Manager) Networks() ([]*Network, error) {
+	return m.Snapshot().Networks()
+}
+
+func (m *Manager) KnownNetworks() ([]*KnownNetwork, error) {
+	return m.Snapshot().KnownNetworks()
+}
+
+func (m *Manager) BasicServiceSets() ([]*BasicServiceSet, error) {
+	return m.Snapshot().BasicServiceSets()
+}
+
+func (m *Manager) propertiesChanged(sig *dbus.Signal) {
+	pc, ok := decodePropertiesChanged(sig, "")
+	if !ok {
+		return
+	}
+	props, ok := m.objects[pc.Path][pc.Interface]
+	if !ok {
+		return
+	}
+	updated := make(map[string]dbus.Variant, len(props)+len(pc.Changed))
+	for name, v := range props {
+		updated[name] = v
+	}
+	for name, v := range pc.Changed {
+		updated[name] = v
+	}
+	for _, name := range pc.Invalidated {
+		delete(updated, name)
+	}
+	m.objects[pc.Path][pc.Interface] = updated
+}
+
+func (m *Manager) interfacesAdded(sig *dbus.Signal) {
+	if len(sig.Body) < 2 {
+		return
+	}
+	p, ok := sig.Body[0].(dbus.ObjectPath)
+	if !ok {
+		return
+	}
+	ifaces, _ := sig.Body[1].(map[string]map[string]dbus.Variant)
+	if m.objects[p] == nil {
+		m.objects[p] = make(map[string]map[string]dbus.Variant, len(ifaces))
+	}
+	for iface, props := range ifaces {
+		m.objects[p][iface] = props
+	}
+}
+
+func (m *Manager) interfacesRemoved(sig *dbus.Signal) {
+	removed, ok := decodeInterfacesRemoved(sig)
+	if !ok {
+		return
+	}
+	for _, iface := range removed.Interfaces {
+		delete(m.objects[removed.Path], iface)
+	}
+	if len(m.objects[removed.Path]) == 0 {
+		delete(m.objects, removed.Path)
+	}
+}
+
+// Properties returns a copy of the properties of iface on
+// the object at p.
+func (s *Snapshot) Properties(p dbus.ObjectPath, iface string) (utils.DBusMapVariant, bool) {
+	props, ok := s.graph.objects[p][iface]
+	if !ok {
+		return nil, false
+	}
+	// The maps are shared with the Manager and other
+	// snapshots.
+	c := make(utils.DBusMapVariant, len(props))
+	for name, v := range props {
+		c[name] = v
+	}
+	return c, true
+}
+
+// Paths returns the sorted paths of the objects that
+// implement iface.
+func (s *Snapshot) Paths(iface string) []dbus.ObjectPath {
+	return s.graph.paths(iface)
+}
+
+func (s *Snapshot) Adapters() ([]*Adapter, error) {
+	return snapshotObjects(s, iwdAdapterIface, newAdapter)
+}
+
+func (s *Snapshot) Devices() ([]*Device, error) {
+	return snapshotObjects(s, iwdDeviceIface, newDevice)
+}
+
+func (s *Snapshot) Stations() ([]*Station, error) {
+	return snapshotObjects(s, iwdStationIface, newStation)
+}
+
+func (s *Snapshot) Networks() ([]*Network, error) {
+	return snapshotObjects(s, iwdNetworkIface, newNetwork)
+}
+
+func (s *Snapshot) KnownNetworks() ([]*KnownNetwork, error) {
+	return snapshotObjects(s, iwdKnownNetworkIface, newKnownNetwork)
+}
+
+func (s *Snapshot) BasicServiceSets() ([]*BasicServiceSet, error) {
+	return snapshotObjects(s, iwdBasicServiceSetIface, newBasicServiceSet)
+}
+
+func snapshotObjects[T any](s *Snapshot, iface string,
+	constructor func(context.Context, dbus.ObjectPath, *Iwd, propertySource) (*T, error)) ([]*T, error) {
+
+	return buildAll(context.Background(), s.graph, iface, s.iwd, constructor)
+}
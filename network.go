@@ -1,8 +1,9 @@
 package iwd
 
 import (
+	"context"
+
 	dbus "github.com/godbus/dbus/v5"
-	"github.com/shtirlic/go-iwd/utils"
 )
 
 const (
@@ -23,45 +24,74 @@ type NetworkInterface interface {
 }
 
 type Network struct {
-	Path         dbus.ObjectPath // /net/connman/iwd/{phy0,phy1,...}/{1,2,...}/Xxx
-	Name         string          // [ro] Network SSID
-	Device       *Device         // [ro]
-	Connected    bool            // [ro]
-	KnownNetwork *KnownNetwork   // [ro] KnownNetwork object corresponding to this Network
-	Type         NetworkType     // [ro] Contains the type of the network
-	iwd          *Iwd
+	Path               dbus.ObjectPath         `iwd:"-"` // /net/connman/iwd/{phy0,phy1,...}/{1,2,...}/Xxx
+	Name               string                  // [ro] Network SSID
+	Device             *Ref[Device]            `iwd:"-"` // [ro]
+	Connected          bool                    // [ro]
+	KnownNetwork       *Ref[KnownNetwork]      `iwd:"-"` // [ro] KnownNetwork object corresponding to this Network
+	Type               NetworkType             // [ro] Contains the type of the network
+	ExtendedServiceSet []*Ref[BasicServiceSet] `iwd:"-"` // [ro] BasicServiceSet objects advertising this Network
+	object
 }
 
 func NewNetwork(p dbus.ObjectPath, i *Iwd) (*Network, error) {
-	objects, err := utils.GetAllProperties(i.conn, iwdService, p, iwdNetworkIface)
+	return NewNetworkContext(context.Background(), p, i)
+}
+
+// NewNetworkContext is the context-aware variant of NewNetwork.
+func NewNetworkContext(ctx context.Context, p dbus.ObjectPath, i *Iwd) (*Network, error) {
+	return newNetwork(ctx, p, i, liveSource{i})
+}
+
+func newNetwork(ctx context.Context, p dbus.ObjectPath, i *Iwd, src propertySource) (*Network, error) {
+	objects, err := src.properties(ctx, p, iwdNetworkIface)
 	if err != nil {
 		return nil, err
 	}
-	var knetwork *KnownNetwork
-	if knetworkValue := objects["KnownNetwork"].Value(); knetworkValue != nil {
-		if knetwork, err = NewKnownNetwork(knetworkValue.(dbus.ObjectPath), i); err != nil {
-			return nil, err
+	var ess []*Ref[BasicServiceSet]
+	if essValue, ok := objects["ExtendedServiceSet"].Value().([]dbus.ObjectPath); ok {
+		for _, bssPath := range essValue {
+			ess = append(ess, newRef(bssPath, iwdBasicServiceSetIface, i, src, newBasicServiceSet))
 		}
 	}
-	var device *Device
-	if deviceValue := objects["Device"].Value(); deviceValue != nil {
-		if device, err = NewDevice(deviceValue.(dbus.ObjectPath), i); err != nil {
-			return nil, err
-		}
+	network := &Network{
+		Path:               p,
+		Device:             optionalRef(objects["Device"], iwdDeviceIface, i, src, newDevice),
+		KnownNetwork:       optionalRef(objects["KnownNetwork"], iwdKnownNetworkIface, i, src, newKnownNetwork),
+		ExtendedServiceSet: ess,
+		object:             i.object(),
+	}
+	if err := decodeProperties(objects, p, iwdNetworkIface, network); err != nil {
+		return nil, err
+	}
+	return network, nil
+}
+
+// Reload the properties of the network from iwd in place.
+// The returned error matches ErrUnknownObject if the
+// network has disappeared since.  Refresh writes the
+// fields, so the network must not be used concurrently.
+func (n *Network) Refresh() error {
+	return n.RefreshContext(context.Background())
+}
+
+// RefreshContext is the context-aware variant of Refresh.
+func (n *Network) RefreshContext(ctx context.Context) error {
+	fresh, err := NewNetworkContext(ctx, n.Path, n.iwd)
+	if err != nil {
+		return err
 	}
-	return &Network{
-		Path:         p,
-		Connected:    objects["Connected"].Value().(bool),
-		Device:       device,
-		KnownNetwork: knetwork,
-		Name:         objects["Name"].Value().(string),
-		Type:         NetworkType(objects["Type"].Value().(string)),
-		iwd:          i,
-	}, nil
+	*n = *fresh
+	return nil
 }
 
 func (n *Network) Connect() error {
-	if _, err := n.iwd.CallServiceMethod(n.Path, callNetworkConnect); err != nil {
+	return n.ConnectContext(context.Background())
+}
+
+// ConnectContext is the context-aware variant of Connect.
+func (n *Network) ConnectContext(ctx context.Context) error {
+	if _, err := n.callMethod(ctx, n.Path, callNetworkConnect); err != nil {
 		return err
 	}
 	return nil
@@ -0,0 +1,104 @@
+package iwd
+
+import (
+	"context"
+	"errors"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	iwdIPv4ConfigurationIface = iwdService + ".IPv4Configuration"
+	iwdIPv6ConfigurationIface = iwdService + ".IPv6Configuration"
+)
+
+// Indicates how the address was obtained.  One of:
+// "auto", "static"
+type ConfigurationMethod string
+
+const (
+	AutoConfigurationMethod   ConfigurationMethod = "auto"
+	StaticConfigurationMethod ConfigurationMethod = "static"
+)
+
+type NetworkConfiguration struct {
+	Path              dbus.ObjectPath     `iwd:"-"` // /net/connman/iwd/{phy0,phy1,...}/{1,2,...}
+	Method            ConfigurationMethod // [ro] Whether the address was obtained automatically or set statically
+	Address           string              // [ro] The address in use
+	PrefixLength      uint8               // [ro] Prefix length of the address
+	Gateway           string              // [ro] Default gateway address, if any
+	Broadcast         string              // [ro] Broadcast address, IPv4 only
+	DomainNameServers []string            // [ro] DNS server addresses, if any
+	DomainNames       []string            // [ro] DNS search domains, if any
+	object
+}
+
+func NewIPv4Configuration(p dbus.ObjectPath, i *Iwd) (*NetworkConfiguration, error) {
+	return NewIPv4ConfigurationContext(context.Background(), p, i)
+}
+
+// NewIPv4ConfigurationContext is the context-aware variant of NewIPv4Configuration.
+func NewIPv4ConfigurationContext(ctx context.Context, p dbus.ObjectPath, i *Iwd) (*NetworkConfiguration, error) {
+	return newNetworkConfiguration(ctx, p, i, liveSource{i}, iwdIPv4ConfigurationIface)
+}
+
+func NewIPv6Configuration(p dbus.ObjectPath, i *Iwd) (*NetworkConfiguration, error) {
+	return NewIPv6ConfigurationContext(context.Background(), p, i)
+}
+
+// NewIPv6ConfigurationContext is the context-aware variant of NewIPv6Configuration.
+func NewIPv6ConfigurationContext(ctx context.Context, p dbus.ObjectPath, i *Iwd) (*NetworkConfiguration, error) {
+	return newNetworkConfiguration(ctx, p, i, liveSource{i}, iwdIPv6ConfigurationIface)
+}
+
+func newNetworkConfiguration(ctx context.Context, p dbus.ObjectPath, i *Iwd, src propertySource,
+	iface string) (*NetworkConfiguration, error) {
+
+	objects, err := src.properties(ctx, p, iface)
+	if err != nil {
+		return nil, err
+	}
+	config := &NetworkConfiguration{
+		Path:   p,
+		object: i.object(),
+	}
+	if err := decodeProperties(objects, p, iface, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Return the IPv4 configuration iwd applied to the
+// station, or nil if iwd does not manage IPv4 for it
+// (see DaemonInfo.NetworkConfigurationEnabled) or the
+// station is not connected.
+func (s *Station) IPv4Configuration() (*NetworkConfiguration, error) {
+	return s.IPv4ConfigurationContext(context.Background())
+}
+
+// IPv4ConfigurationContext is the context-aware variant of IPv4Configuration.
+func (s *Station) IPv4ConfigurationContext(ctx context.Context) (*NetworkConfiguration, error) {
+	return s.networkConfiguration(ctx, iwdIPv4ConfigurationIface)
+}
+
+// Return the IPv6 configuration iwd applied to the
+// station, or nil if iwd does not manage IPv6 for it
+// or the station is not connected.
+func (s *Station) IPv6Configuration() (*NetworkConfiguration, error) {
+	return s.IPv6ConfigurationContext(context.Background())
+}
+
+// IPv6ConfigurationContext is the context-aware variant of IPv6Configuration.
+func (s *Station) IPv6ConfigurationContext(ctx context.Context) (*NetworkConfiguration, error) {
+	return s.networkConfiguration(ctx, iwdIPv6ConfigurationIface)
+}
+
+func (s *Station) networkConfiguration(ctx context.Context, iface string) (*NetworkConfiguration, error) {
+	// iwd adds the configuration interfaces to the station
+	// object once it configured the network.
+	config, err := newNetworkConfiguration(ctx, s.Path, s.iwd, liveSource{s.iwd}, iface)
+	if errors.Is(err, ErrUnknownObject) {
+		return nil, nil
+	}
+	return config, err
+}
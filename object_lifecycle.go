@@ -0,0 +1,99 @@
+package iwd
+
+import (
+	"context"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	dbusObjectManagerIface = "org.freedesktop.DBus.ObjectManager"
+
+	signalInterfacesAdded   = "InterfacesAdded"
+	signalInterfacesRemoved = "InterfacesRemoved"
+)
+
+// ObjectAdded reports interfaces that appeared on the iwd
+// object at Path, e.g. when an adapter is plugged in or a
+// scan finds a new network.  The field matching each added
+// interface is set to the object built from the properties
+// sent with the signal; Err is set if one of them could
+// not be decoded.
+type ObjectAdded struct {
+	Path         dbus.ObjectPath
+	Interfaces   []string
+	Adapter      *Adapter
+	Device       *Device
+	Station      *Station
+	Network      *Network
+	KnownNetwork *KnownNetwork
+	Err          error
+}
+
+// ObjectRemoved reports interfaces that were removed from
+// the iwd object at Path.
+type ObjectRemoved struct {
+	Path       dbus.ObjectPath
+	Interfaces []string
+}
+
+// Subscribe to objects and interfaces added to iwd.
+func (i *Iwd) SubscribeObjectAdded(ctx context.Context) (*Subscription[ObjectAdded], error) {
+	return subscribe(ctx, i, "", dbusObjectManagerIface, signalInterfacesAdded,
+		func(sig *dbus.Signal) (ObjectAdded, bool) {
+			return i.decodeInterfacesAdded(sig)
+		})
+}
+
+// Subscribe to objects and interfaces removed from iwd.
+func (i *Iwd) SubscribeObjectRemoved(ctx context.Context) (*Subscription[ObjectRemoved], error) {
+	return subscribe(ctx, i, "", dbusObjectManagerIface, signalInterfacesRemoved, decodeInterfacesRemoved)
+}
+
+func (i *Iwd) decodeInterfacesAdded(sig *dbus.Signal) (ObjectAdded, bool) {
+	if len(sig.Body) < 2 {
+		return ObjectAdded{}, false
+	}
+	p, ok := sig.Body[0].(dbus.ObjectPath)
+	if !ok {
+		return ObjectAdded{}, false
+	}
+	ifaces, _ := sig.Body[1].(map[string]map[string]dbus.Variant)
+	added := ObjectAdded{Path: p}
+	// The signal carries the properties of the added
+	// interfaces, so no round trip to iwd is needed.
+	ctx := context.Background()
+	g := signalGraph(i, p, ifaces)
+	var err error
+	for iface := range ifaces {
+		added.Interfaces = append(added.Interfaces, iface)
+		switch iface {
+		case iwdAdapterIface:
+			added.Adapter, err = build(ctx, g, p, iface, i, newAdapter)
+		case iwdDeviceIface:
+			added.Device, err = build(ctx, g, p, iface, i, newDevice)
+		case iwdStationIface:
+			added.Station, err = build(ctx, g, p, iface, i, newStation)
+		case iwdNetworkIface:
+			added.Network, err = build(ctx, g, p, iface, i, newNetwork)
+		case iwdKnownNetworkIface:
+			added.KnownNetwork, err = build(ctx, g, p, iface, i, newKnownNetwork)
+		}
+		if err != nil && added.Err == nil {
+			added.Err = err
+		}
+	}
+	return added, true
+}
+
+func decodeInterfacesRemoved(sig *dbus.Signal) (ObjectRemoved, bool) {
+	if len(sig.Body) < 2 {
+		return ObjectRemoved{}, false
+	}
+	p, ok := sig.Body[0].(dbus.ObjectPath)
+	if !ok {
+		return ObjectRemoved{}, false
+	}
+	ifaces, _ := sig.Body[1].([]string)
+	return ObjectRemoved{Path: p, Interfaces: ifaces}, true
+}
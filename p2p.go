@@ -0,0 +1,207 @@
+package iwd
+
+import (
+	"context"
+	"errors"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	iwdP2PDeviceIface = iwdService + ".p2p.Device"
+	iwdP2PPeerIface   = iwdService + ".p2p.Peer"
+
+	callP2PDeviceRequestDiscovery = iwdP2PDeviceIface + ".RequestDiscovery"
+	callP2PDeviceReleaseDiscovery = iwdP2PDeviceIface + ".ReleaseDiscovery"
+	callP2PDeviceGetPeers         = iwdP2PDeviceIface + ".GetPeers"
+	callP2PPeerDisconnect         = iwdP2PPeerIface + ".Disconnect"
+)
+
+type P2PDevice struct {
+	Path                 dbus.ObjectPath `iwd:"-"` // /net/connman/iwd/{phy0,phy1,...}/p2p
+	Enabled              bool            // [rw] Whether the P2P functionality of the device is enabled
+	Name                 string          // [rw] Device name advertised to other P2P devices
+	AvailableConnections uint16          // [ro] Number of P2P connections that can still be established
+	object
+}
+
+type Peer struct {
+	Path               dbus.ObjectPath `iwd:"-"` // /net/connman/iwd/{phy0,phy1,...}/p2p_peers/{aa_bb_cc_dd_ee_ff}
+	Name               string          // [ro] P2P device name of the peer
+	DeviceCategory     string          // [ro] The peer's primary device category
+	DeviceSubcategory  string          // [ro] The peer's primary device subcategory
+	Device             *Ref[P2PDevice] `iwd:"-"` // [ro] The local P2P device the peer was discovered on
+	Connected          bool            // [ro] Whether there is a connection to the peer
+	ConnectedInterface string          // [ro] Network interface of the connection, if connected
+	ConnectedIP        string          // [ro] Peer's IP address, if connected and assigned
+	object
+}
+
+type PeerWithSignal struct {
+	*Peer
+	SignalStrength SignalStrength
+}
+
+func NewP2PDevice(p dbus.ObjectPath, i *Iwd) (*P2PDevice, error) {
+	return NewP2PDeviceContext(context.Background(), p, i)
+}
+
+// NewP2PDeviceContext is the context-aware variant of NewP2PDevice.
+func NewP2PDeviceContext(ctx context.Context, p dbus.ObjectPath, i *Iwd) (*P2PDevice, error) {
+	return newP2PDevice(ctx, p, i, liveSource{i})
+}
+
+func newP2PDevice(ctx context.Context, p dbus.ObjectPath, i *Iwd, src propertySource) (*P2PDevice, error) {
+	objects, err := src.properties(ctx, p, iwdP2PDeviceIface)
+	if err != nil {
+		return nil, err
+	}
+	device := &P2PDevice{
+		Path:   p,
+		object: i.object(),
+	}
+	if err := decodeProperties(objects, p, iwdP2PDeviceIface, device); err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+func NewPeer(p dbus.ObjectPath, i *Iwd) (*Peer, error) {
+	return NewPeerContext(context.Background(), p, i)
+}
+
+// NewPeerContext is the context-aware variant of NewPeer.
+func NewPeerContext(ctx context.Context, p dbus.ObjectPath, i *Iwd) (*Peer, error) {
+	return newPeer(ctx, p, i, liveSource{i})
+}
+
+func newPeer(ctx context.Context, p dbus.ObjectPath, i *Iwd, src propertySource) (*Peer, error) {
+	objects, err := src.properties(ctx, p, iwdP2PPeerIface)
+	if err != nil {
+		return nil, err
+	}
+	peer := &Peer{
+		Path:   p,
+		Device: optionalRef(objects["Device"], iwdP2PDeviceIface, i, src, newP2PDevice),
+		object: i.object(),
+	}
+	if err := decodeProperties(objects, p, iwdP2PPeerIface, peer); err != nil {
+		return nil, err
+	}
+	return peer, nil
+}
+
+// Request that the device start a P2P discovery.  While
+// at least one client holds a discovery request, the
+// device keeps scanning for peers.  Each call must be
+// paired with a ReleaseDiscovery() call.
+func (d *P2PDevice) RequestDiscovery() error {
+	return d.RequestDiscoveryContext(context.Background())
+}
+
+// RequestDiscoveryContext is the context-aware variant of RequestDiscovery.
+func (d *P2PDevice) RequestDiscoveryContext(ctx context.Context) error {
+	if _, err := d.callMethod(ctx, d.Path, callP2PDeviceRequestDiscovery); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Release a discovery request made with
+// RequestDiscovery().
+func (d *P2PDevice) ReleaseDiscovery() error {
+	return d.ReleaseDiscoveryContext(context.Background())
+}
+
+// ReleaseDiscoveryContext is the context-aware variant of ReleaseDiscovery.
+func (d *P2PDevice) ReleaseDiscoveryContext(ctx context.Context) error {
+	if _, err := d.callMethod(ctx, d.Path, callP2PDeviceReleaseDiscovery); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Return the list (possibly empty) of detected P2P peers
+// and their signal strength.
+func (d *P2PDevice) GetPeers() ([]PeerWithSignal, error) {
+	return d.GetPeersContext(context.Background())
+}
+
+// GetPeersContext is the context-aware variant of GetPeers.
+func (d *P2PDevice) GetPeersContext(ctx context.Context) ([]PeerWithSignal, error) {
+	call, err := d.callMethod(ctx, d.Path, callP2PDeviceGetPeers)
+	if err != nil {
+		return nil, err
+	}
+	// Build all entries from one copy of the object tree
+	// rather than querying every one of them.
+	g, err := loadGraph(ctx, d.iwd)
+	if err != nil {
+		return nil, err
+	}
+	var objects []pathWithSignal
+	var peers []PeerWithSignal
+	if err = call.Store(&objects); err != nil {
+		return nil, err
+	}
+	for _, o := range objects {
+		peer, err := build(ctx, g, o.Path, iwdP2PPeerIface, d.iwd, newPeer)
+		if errors.Is(err, ErrUnknownObject) {
+			// Peers that vanished between the two calls are
+			// left out.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		peers = append(peers, PeerWithSignal{peer, o.Signal})
+	}
+	return peers, nil
+}
+
+// Disconnect from the peer.
+func (p *Peer) Disconnect() error {
+	return p.DisconnectContext(context.Background())
+}
+
+// DisconnectContext is the context-aware variant of Disconnect.
+func (p *Peer) DisconnectContext(ctx context.Context) error {
+	if _, err := p.callMethod(ctx, p.Path, callP2PPeerDisconnect); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Return the SimpleConfiguration interface of the peer,
+// used to connect to it with PushButton() or StartPin().
+func (p *Peer) WSC() (*WSC, error) {
+	return NewWSC(p.Path, p.iwd)
+}
+
+// Enable or disable the P2P functionality of the device.
+func (d *P2PDevice) SetEnabled(enabled bool) error {
+	return d.SetEnabledContext(context.Background(), enabled)
+}
+
+// SetEnabledContext is the context-aware variant of SetEnabled.
+func (d *P2PDevice) SetEnabledContext(ctx context.Context, enabled bool) error {
+	if err := d.setProperty(ctx, d.Path, iwdP2PDeviceIface, "Enabled", enabled); err != nil {
+		return err
+	}
+	d.Enabled = enabled
+	return nil
+}
+
+// Change the device name advertised to other P2P devices.
+func (d *P2PDevice) SetName(name string) error {
+	return d.SetNameContext(context.Background(), name)
+}
+
+// SetNameContext is the context-aware variant of SetName.
+func (d *P2PDevice) SetNameContext(ctx context.Context, name string) error {
+	if err := d.setProperty(ctx, d.Path, iwdP2PDeviceIface, "Name", name); err != nil {
+		return err
+	}
+	d.Name = name
+	return nil
+}
@@ -0,0 +1,204 @@
+package iwd
+
+import (
+	"context"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	dbusPropertiesIface           = "org.freedesktop.DBus.Properties"
+	signalPropertiesChangedMember = "PropertiesChanged"
+)
+
+// PropertiesChanged reports the properties of one iwd
+// interface that changed on the object at Path.  Optional
+// properties that disappeared, e.g. Station's
+// ConnectedNetwork after a disconnect, are listed in
+// Invalidated.
+type PropertiesChanged struct {
+	Path        dbus.ObjectPath
+	Interface   string
+	Changed     map[string]dbus.Variant
+	Invalidated []string
+}
+
+// Changes of the net.connman.iwd.Adapter properties.  Nil
+// fields were not changed.
+type AdapterChanged struct {
+	PropertiesChanged
+	Powered *bool
+}
+
+// Changes of the net.connman.iwd.Device properties.  Nil
+// fields were not changed.
+type DeviceChanged struct {
+	PropertiesChanged
+	Powered *bool
+	Mode    *DeviceMode
+	Name    *string
+	Address *string
+}
+
+// Changes of the net.connman.iwd.Station properties.  Nil
+// fields were not changed; object path fields point to an
+// empty path when the property was removed.
+type StationChanged struct {
+	PropertiesChanged
+	State                *ConnectionState
+	Scanning             *bool
+	ConnectedNetwork     *dbus.ObjectPath
+	ConnectedAccessPoint *dbus.ObjectPath
+}
+
+// Changes of the net.connman.iwd.Network properties.  Nil
+// fields were not changed; object path fields point to an
+// empty path when the property was removed.
+type NetworkChanged struct {
+	PropertiesChanged
+	Connected    *bool
+	KnownNetwork *dbus.ObjectPath
+	Name         *string
+}
+
+// Changes of the net.connman.iwd.KnownNetwork properties.
+// Nil fields were not changed.
+type KnownNetworkChanged struct {
+	PropertiesChanged
+	AutoConnect       *bool
+	LastConnectedTime *string
+	Name              *string
+}
+
+// Subscribe to property changes of every iwd object and
+// interface.
+func (i *Iwd) SubscribePropertiesChanged(ctx context.Context) (*Subscription[PropertiesChanged], error) {
+	return subscribe(ctx, i, "", dbusPropertiesIface, signalPropertiesChangedMember,
+		func(sig *dbus.Signal) (PropertiesChanged, bool) {
+			return decodePropertiesChanged(sig, "")
+		})
+}
+
+// Subscribe to property changes of all adapters.
+func (i *Iwd) SubscribeAdapterChanged(ctx context.Context) (*Subscription[AdapterChanged], error) {
+	return subscribe(ctx, i, "", dbusPropertiesIface, signalPropertiesChangedMember,
+		func(sig *dbus.Signal) (AdapterChanged, bool) {
+			pc, ok := decodePropertiesChanged(sig, iwdAdapterIface)
+			return AdapterChanged{
+				PropertiesChanged: pc,
+				Powered:           changedValue[bool](pc, "Powered"),
+			}, ok
+		})
+}
+
+// Subscribe to property changes of all devices.
+func (i *Iwd) SubscribeDeviceChanged(ctx context.Context) (*Subscription[DeviceChanged], error) {
+	return subscribe(ctx, i, "", dbusPropertiesIface, signalPropertiesChangedMember,
+		func(sig *dbus.Signal) (DeviceChanged, bool) {
+			pc, ok := decodePropertiesChanged(sig, iwdDeviceIface)
+			return DeviceChanged{
+				PropertiesChanged: pc,
+				Powered:           changedValue[bool](pc, "Powered"),
+				Mode:              changedString[DeviceMode](pc, "Mode"),
+				Name:              changedValue[string](pc, "Name"),
+				Address:           changedValue[string](pc, "Address"),
+			}, ok
+		})
+}
+
+// Subscribe to property changes of all stations.
+func (i *Iwd) SubscribeStationChanged(ctx context.Context) (*Subscription[StationChanged], error) {
+	return subscribe(ctx, i, "", dbusPropertiesIface, signalPropertiesChangedMember,
+		func(sig *dbus.Signal) (StationChanged, bool) {
+			pc, ok := decodePropertiesChanged(sig, iwdStationIface)
+			return StationChanged{
+				PropertiesChanged:    pc,
+				State:                changedString[ConnectionState](pc, "State"),
+				Scanning:             changedValue[bool](pc, "Scanning"),
+				ConnectedNetwork:     changedPath(pc, "ConnectedNetwork"),
+				ConnectedAccessPoint: changedPath(pc, "ConnectedAccessPoint"),
+			}, ok
+		})
+}
+
+// Subscribe to property changes of all networks.
+func (i *Iwd) SubscribeNetworkChanged(ctx context.Context) (*Subscription[NetworkChanged], error) {
+	return subscribe(ctx, i, "", dbusPropertiesIface, signalPropertiesChangedMember,
+		func(sig *dbus.Signal) (NetworkChanged, bool) {
+			pc, ok := decodePropertiesChanged(sig, iwdNetworkIface)
+			return NetworkChanged{
+				PropertiesChanged: pc,
+				Connected:         changedValue[bool](pc, "Connected"),
+				KnownNetwork:      changedPath(pc, "KnownNetwork"),
+				Name:              changedValue[string](pc, "Name"),
+			}, ok
+		})
+}
+
+// Subscribe to property changes of all known networks.
+func (i *Iwd) SubscribeKnownNetworkChanged(ctx context.Context) (*Subscription[KnownNetworkChanged], error) {
+	return subscribe(ctx, i, "", dbusPropertiesIface, signalPropertiesChangedMember,
+		func(sig *dbus.Signal) (KnownNetworkChanged, bool) {
+			pc, ok := decodePropertiesChanged(sig, iwdKnownNetworkIface)
+			return KnownNetworkChanged{
+				PropertiesChanged: pc,
+				AutoConnect:       changedValue[bool](pc, "AutoConnect"),
+				LastConnectedTime: changedValue[string](pc, "LastConnectedTime"),
+				Name:              changedValue[string](pc, "Name"),
+			}, ok
+		})
+}
+
+// decodePropertiesChanged parses the PropertiesChanged
+// signal body.  It reports false for malformed signals and,
+// if iface is not empty, for other interfaces.
+func decodePropertiesChanged(sig *dbus.Signal, iface string) (PropertiesChanged, bool) {
+	if len(sig.Body) < 3 {
+		return PropertiesChanged{}, false
+	}
+	pc := PropertiesChanged{Path: sig.Path}
+	var ok bool
+	if pc.Interface, ok = sig.Body[0].(string); !ok {
+		return PropertiesChanged{}, false
+	}
+	if iface != "" && pc.Interface != iface {
+		return PropertiesChanged{}, false
+	}
+	pc.Changed, _ = sig.Body[1].(map[string]dbus.Variant)
+	pc.Invalidated, _ = sig.Body[2].([]string)
+	return pc, true
+}
+
+func changedValue[T any](pc PropertiesChanged, name string) *T {
+	v, ok := pc.Changed[name]
+	if !ok {
+		return nil
+	}
+	t, ok := v.Value().(T)
+	if !ok {
+		return nil
+	}
+	return &t
+}
+
+func changedString[T ~string](pc PropertiesChanged, name string) *T {
+	s := changedValue[string](pc, name)
+	if s == nil {
+		return nil
+	}
+	t := T(*s)
+	return &t
+}
+
+func changedPath(pc PropertiesChanged, name string) *dbus.ObjectPath {
+	if p := changedValue[dbus.ObjectPath](pc, name); p != nil {
+		return p
+	}
+	for _, invalidated := range pc.Invalidated {
+		if invalidated == name {
+			var p dbus.ObjectPath
+			return &p
+		}
+	}
+	return nil
+}
@@ -0,0 +1,600 @@
+package iwd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// RecordedMessage is one entry of a recording made with
+// Iwd.Record: a method call with its reply or error, or a
+// signal received from iwd.
+type RecordedMessage struct {
+	Signal bool            // Whether the entry is a signal rather than a method call
+	Agent  bool            // Whether iwd made the call, on an agent exported by the Iwd
+	Path   dbus.ObjectPath // Object the call was made on or the signal emitted by
+	Name   string          // Interface and member, e.g. net.connman.iwd.Station.Scan
+	Args   []interface{}   // Call arguments or signal body
+	Reply  []interface{}   // Reply of a successful call
+	Error  *dbus.Error     // Error reply of a failed call
+}
+
+// recordedValue is the JSON form of a D-Bus value: its
+// signature and a JSON rendering of the value that keeps
+// the exact D-Bus types.
+type recordedValue struct {
+	Signature string          `json:"sig"`
+	Value     json.RawMessage `json:"value"`
+}
+
+type recordedError struct {
+	Name string          `json:"name"`
+	Body []recordedValue `json:"body,omitempty"`
+}
+
+type recordedMessage struct {
+	Signal bool            `json:"signal,omitempty"`
+	Agent  bool            `json:"agent,omitempty"`
+	Path   dbus.ObjectPath `json:"path"`
+	Name   string          `json:"name"`
+	Args   []recordedValue `json:"args,omitempty"`
+	Reply  []recordedValue `json:"reply,omitempty"`
+	Error  *recordedError  `json:"error,omitempty"`
+}
+
+// Record writes every method call made to iwd, with its
+// reply or error, every call iwd makes on the agents, with
+// the reply it got, and every signal received from iwd to
+// w, one JSON object per line, until Record is called
+// again.  Pass nil to stop recording.  Calls that fail
+// without an error reply from iwd, e.g. because ctx was
+// canceled, are recorded as
+// org.freedesktop.DBus.Error.Failed.  The recording holds
+// the credentials the agents return.  A message that
+// cannot be encoded or written is left out rather than
+// failing the call it belongs to; RecordErr reports the
+// first such error.
+//
+// Read recordings with ReadRecording; iwdtest can replay
+// them in place of iwd.
+func (i *Iwd) Record(w io.Writer) {
+	i.recMu.Lock()
+	defer i.recMu.Unlock()
+	i.recErr = nil
+	if w == nil {
+		i.rec = nil
+		return
+	}
+	i.rec = json.NewEncoder(w)
+}
+
+// RecordErr returns the first error encoding or writing a
+// message since Record was last called.
+func (i *Iwd) RecordErr() error {
+	i.recMu.Lock()
+	defer i.recMu.Unlock()
+	return i.recErr
+}
+
+// ReadRecording reads the messages written by Iwd.Record.
+// Values are restored with their original D-Bus types:
+// arrays, dictionaries and structs become typed slices, maps
+// and structs that encode to the recorded signatures.
+func ReadRecording(r io.Reader) ([]RecordedMessage, error) {
+	var messages []RecordedMessage
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var m RecordedMessage
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			return nil, fmt.Errorf("iwd: recording line %d: %w", line, err)
+		}
+		messages = append(messages, m)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func (i *Iwd) recordCall(path dbus.ObjectPath, method string, args, reply []interface{}, err error) {
+	m := RecordedMessage{Path: path, Name: method, Args: args}
+	var dbusErr dbus.Error
+	switch {
+	case errors.As(err, &dbusErr):
+		m.Error = &dbusErr
+	case err != nil:
+		m.Error = &dbus.Error{Name: "org.freedesktop.DBus.Error.Failed", Body: []interface{}{err.Error()}}
+	default:
+		m.Reply = reply
+	}
+	i.record(m)
+}
+
+// recordAgentCall records a call iwd made on the object
+// exported at path, with the reply it got.
+func (i *Iwd) recordAgentCall(path dbus.ObjectPath, method string, args, reply []interface{}, err *dbus.Error) {
+	m := RecordedMessage{Agent: true, Path: path, Name: method, Args: args}
+	if err != nil {
+		m.Error = err
+	} else {
+		m.Reply = reply
+	}
+	i.record(m)
+}
+
+func (i *Iwd) recordSignal(sig *dbus.Signal) {
+	i.record(RecordedMessage{Signal: true, Path: sig.Path, Name: sig.Name, Args: sig.Body})
+}
+
+func (i *Iwd) record(m RecordedMessage) {
+	i.recMu.Lock()
+	defer i.recMu.Unlock()
+	if i.rec == nil {
+		return
+	}
+	if err := i.rec.Encode(m); err != nil && i.recErr == nil {
+		i.recErr = err
+	}
+}
+
+func (m RecordedMessage) MarshalJSON() ([]byte, error) {
+	out := recordedMessage{Signal: m.Signal, Agent: m.Agent, Path: m.Path, Name: m.Name}
+	var err error
+	if out.Args, err = encodeValues(m.Args); err != nil {
+		return nil, err
+	}
+	if out.Reply, err = encodeValues(m.Reply); err != nil {
+		return nil, err
+	}
+	if m.Error != nil {
+		out.Error = &recordedError{Name: m.Error.Name}
+		if out.Error.Body, err = encodeValues(m.Error.Body); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(out)
+}
+
+func (m *RecordedMessage) UnmarshalJSON(data []byte) error {
+	var in recordedMessage
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*m = RecordedMessage{Signal: in.Signal, Agent: in.Agent, Path: in.Path, Name: in.Name}
+	var err error
+	if m.Args, err = decodeValues(in.Args); err != nil {
+		return err
+	}
+	if m.Reply, err = decodeValues(in.Reply); err != nil {
+		return err
+	}
+	if in.Error != nil {
+		m.Error = &dbus.Error{Name: in.Error.Name}
+		if m.Error.Body, err = decodeValues(in.Error.Body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeValues(vs []interface{}) ([]recordedValue, error) {
+	var out []recordedValue
+	for _, v := range vs {
+		sig := recordedSignature(v)
+		j, err := encodeValue(reflect.ValueOf(v), sig)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := json.Marshal(j)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, recordedValue{Signature: sig, Value: raw})
+	}
+	return out, nil
+}
+
+func decodeValues(rvs []recordedValue) ([]interface{}, error) {
+	var out []interface{}
+	for _, rv := range rvs {
+		if _, err := dbus.ParseSignature(rv.Signature); err != nil {
+			return nil, err
+		}
+		var j interface{}
+		dec := json.NewDecoder(bytes.NewReader(rv.Value))
+		dec.UseNumber()
+		if err := dec.Decode(&j); err != nil {
+			return nil, err
+		}
+		v, err := decodeValue(j, rv.Signature)
+		if err != nil {
+			return nil, fmt.Errorf("value %s of type %s: %w", rv.Value, rv.Signature, err)
+		}
+		out = append(out, v.Interface())
+	}
+	return out, nil
+}
+
+// recordedSignature returns the D-Bus signature of a value
+// as sent by this package or decoded by godbus.  godbus
+// decodes structs as []interface{}, so their signature
+// is taken from the elements.  The element type of an empty
+// array of structs is unknown and recorded as variant.
+func recordedSignature(v interface{}) string {
+	switch v := v.(type) {
+	case dbus.Variant:
+		return "v"
+	case []interface{}:
+		sig := "("
+		for _, e := range v {
+			sig += recordedSignature(e)
+		}
+		return sig + ")"
+	}
+	t := reflect.TypeOf(v)
+	if !hasInterface(t) {
+		return dbus.SignatureOfType(t).String()
+	}
+	rv := reflect.ValueOf(v)
+	switch t.Kind() {
+	case reflect.Slice:
+		if rv.Len() == 0 {
+			return "av"
+		}
+		return "a" + recordedSignature(rv.Index(0).Interface())
+	case reflect.Map:
+		key := dbus.SignatureOfType(t.Key()).String()
+		if rv.Len() == 0 {
+			return "a{" + key + "v}"
+		}
+		iter := rv.MapRange()
+		iter.Next()
+		return "a{" + key + recordedSignature(iter.Value().Interface()) + "}"
+	}
+	return dbus.SignatureOf(v).String()
+}
+
+func hasInterface(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Slice, reflect.Array, reflect.Pointer:
+		return hasInterface(t.Elem())
+	case reflect.Map:
+		return hasInterface(t.Key()) || hasInterface(t.Elem())
+	}
+	return false
+}
+
+// splitSignature splits a signature into its complete
+// types.
+func splitSignature(sig string) ([]string, error) {
+	var types []string
+	for sig != "" {
+		n, err := completeTypeLen(sig)
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, sig[:n])
+		sig = sig[n:]
+	}
+	return types, nil
+}
+
+func completeTypeLen(sig string) (int, error) {
+	switch sig[0] {
+	case 'a':
+		if len(sig) < 2 {
+			return 0, errors.New("incomplete array signature")
+		}
+		n, err := completeTypeLen(sig[1:])
+		return n + 1, err
+	case '(', '{':
+		closing := map[byte]byte{'(': ')', '{': '}'}[sig[0]]
+		depth := 0
+		for n := 0; n < len(sig); n++ {
+			switch sig[n] {
+			case '(', '{':
+				depth++
+			case ')', '}':
+				depth--
+				if depth == 0 {
+					if sig[n] != closing {
+						return 0, fmt.Errorf("mismatched %q in signature", sig[n])
+					}
+					return n + 1, nil
+				}
+			}
+		}
+		return 0, errors.New("unterminated signature")
+	}
+	return 1, nil
+}
+
+// encodeValue renders v of type sig as a JSON value:
+// variants as {"sig", "value"}, structs as arrays and
+// dictionaries as arrays of key-value pairs sorted by key,
+// since their keys need not be strings.
+func encodeValue(v reflect.Value, sig string) (interface{}, error) {
+	for v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	switch sig[0] {
+	case 'v':
+		variant, ok := v.Interface().(dbus.Variant)
+		if !ok {
+			return nil, fmt.Errorf("cannot record %s as variant", v.Type())
+		}
+		inner := variant.Signature().String()
+		j, err := encodeValue(reflect.ValueOf(variant.Value()), inner)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := json.Marshal(j)
+		if err != nil {
+			return nil, err
+		}
+		return recordedValue{Signature: inner, Value: raw}, nil
+	case '(':
+		types, err := splitSignature(sig[1 : len(sig)-1])
+		if err != nil {
+			return nil, err
+		}
+		var n int
+		if v.Kind() == reflect.Struct {
+			n = v.NumField()
+		} else {
+			n = v.Len()
+		}
+		if n != len(types) {
+			return nil, fmt.Errorf("cannot record %s as %s", v.Type(), sig)
+		}
+		fields := make([]interface{}, n)
+		for k := range types {
+			var field reflect.Value
+			if v.Kind() == reflect.Struct {
+				field = v.Field(k)
+			} else {
+				field = v.Index(k)
+			}
+			if fields[k], err = encodeValue(field, types[k]); err != nil {
+				return nil, err
+			}
+		}
+		return fields, nil
+	case 'a':
+		if sig[1] == '{' {
+			types, err := splitSignature(sig[2 : len(sig)-1])
+			if err != nil {
+				return nil, err
+			}
+			pairs := make([][2]interface{}, 0, v.Len())
+			iter := v.MapRange()
+			for iter.Next() {
+				key, err := encodeValue(iter.Key(), types[0])
+				if err != nil {
+					return nil, err
+				}
+				value, err := encodeValue(iter.Value(), types[1])
+				if err != nil {
+					return nil, err
+				}
+				pairs = append(pairs, [2]interface{}{key, value})
+			}
+			sort.Slice(pairs, func(a, b int) bool {
+				return fmt.Sprint(pairs[a][0]) < fmt.Sprint(pairs[b][0])
+			})
+			return pairs, nil
+		}
+		elems := make([]interface{}, v.Len())
+		for k := range elems {
+			var err error
+			if elems[k], err = encodeValue(v.Index(k), sig[1:]); err != nil {
+				return nil, err
+			}
+		}
+		return elems, nil
+	case 'g':
+		return v.Interface().(dbus.Signature).String(), nil
+	}
+	return v.Interface(), nil
+}
+
+var basicTypes = map[byte]reflect.Type{
+	'y': reflect.TypeOf(byte(0)),
+	'b': reflect.TypeOf(false),
+	'n': reflect.TypeOf(int16(0)),
+	'q': reflect.TypeOf(uint16(0)),
+	'i': reflect.TypeOf(int32(0)),
+	'u': reflect.TypeOf(uint32(0)),
+	'x': reflect.TypeOf(int64(0)),
+	't': reflect.TypeOf(uint64(0)),
+	'd': reflect.TypeOf(float64(0)),
+	's': reflect.TypeOf(""),
+	'o': reflect.TypeOf(dbus.ObjectPath("")),
+	'g': reflect.TypeOf(dbus.Signature{}),
+	'h': reflect.TypeOf(dbus.UnixFDIndex(0)),
+	'v': reflect.TypeOf(dbus.Variant{}),
+}
+
+// recordedType returns a Go type that godbus encodes as
+// sig; structs get generated types with fields F0, F1, ...
+func recordedType(sig string) (reflect.Type, error) {
+	if t, ok := basicTypes[sig[0]]; ok {
+		return t, nil
+	}
+	switch sig[0] {
+	case '(':
+		types, err := splitSignature(sig[1 : len(sig)-1])
+		if err != nil {
+			return nil, err
+		}
+		fields := make([]reflect.StructField, len(types))
+		for k, s := range types {
+			t, err := recordedType(s)
+			if err != nil {
+				return nil, err
+			}
+			fields[k] = reflect.StructField{Name: "F" + strconv.Itoa(k), Type: t}
+		}
+		return reflect.StructOf(fields), nil
+	case 'a':
+		if sig[1] == '{' {
+			types, err := splitSignature(sig[2 : len(sig)-1])
+			if err != nil {
+				return nil, err
+			}
+			key, err := recordedType(types[0])
+			if err != nil {
+				return nil, err
+			}
+			elem, err := recordedType(types[1])
+			if err != nil {
+				return nil, err
+			}
+			return reflect.MapOf(key, elem), nil
+		}
+		elem, err := recordedType(sig[1:])
+		if err != nil {
+			return nil, err
+		}
+		return reflect.SliceOf(elem), nil
+	}
+	return nil, fmt.Errorf("unsupported signature %q", sig)
+}
+
+// decodeValue is the inverse of encodeValue.  j holds
+// numbers as json.Number so that 64 bit integers keep their
+// precision.
+func decodeValue(j interface{}, sig string) (reflect.Value, error) {
+	t, err := recordedType(sig)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	mismatch := fmt.Errorf("cannot decode %v as %s", j, sig)
+	switch sig[0] {
+	case 'v':
+		obj, ok := j.(map[string]interface{})
+		if !ok {
+			return reflect.Value{}, mismatch
+		}
+		inner, _ := obj["sig"].(string)
+		if _, err := dbus.ParseSignature(inner); err != nil || inner == "" {
+			return reflect.Value{}, mismatch
+		}
+		value, err := decodeValue(obj["value"], inner)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(dbus.MakeVariantWithSignature(value.Interface(), dbus.ParseSignatureMust(inner))), nil
+	case '(':
+		fields, ok := j.([]interface{})
+		if !ok || len(fields) != t.NumField() {
+			return reflect.Value{}, mismatch
+		}
+		types, _ := splitSignature(sig[1 : len(sig)-1])
+		v := reflect.New(t).Elem()
+		for k := range fields {
+			field, err := decodeValue(fields[k], types[k])
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v.Field(k).Set(field)
+		}
+		return v, nil
+	case 'a':
+		elems, ok := j.([]interface{})
+		if !ok {
+			return reflect.Value{}, mismatch
+		}
+		if sig[1] == '{' {
+			types, _ := splitSignature(sig[2 : len(sig)-1])
+			v := reflect.MakeMapWithSize(t, len(elems))
+			for _, e := range elems {
+				pair, ok := e.([]interface{})
+				if !ok || len(pair) != 2 {
+					return reflect.Value{}, mismatch
+				}
+				key, err := decodeValue(pair[0], types[0])
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				value, err := decodeValue(pair[1], types[1])
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				v.SetMapIndex(key, value)
+			}
+			return v, nil
+		}
+		v := reflect.MakeSlice(t, len(elems), len(elems))
+		for k, e := range elems {
+			elem, err := decodeValue(e, sig[1:])
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v.Index(k).Set(elem)
+		}
+		return v, nil
+	case 'b':
+		b, ok := j.(bool)
+		if !ok {
+			return reflect.Value{}, mismatch
+		}
+		return reflect.ValueOf(b), nil
+	case 's', 'o':
+		s, ok := j.(string)
+		if !ok {
+			return reflect.Value{}, mismatch
+		}
+		return reflect.ValueOf(s).Convert(t), nil
+	case 'g':
+		s, ok := j.(string)
+		if !ok {
+			return reflect.Value{}, mismatch
+		}
+		g, err := dbus.ParseSignature(s)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(g), nil
+	}
+	n, ok := j.(json.Number)
+	if !ok {
+		return reflect.Value{}, mismatch
+	}
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Float64:
+		f, err := n.Float64()
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetFloat(f)
+	case reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(n.String(), 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetInt(i)
+	default:
+		u, err := strconv.ParseUint(n.String(), 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetUint(u)
+	}
+	return v, nil
+}
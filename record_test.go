@@ -0,0 +1,246 @@
+package iwd
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// encodeBody returns the signature and wire encoding of a
+// message body.
+func encodeBody(t *testing.T, values []interface{}) (dbus.Signature, []byte) {
+	t.Helper()
+	sig := dbus.SignatureOf(values...)
+	msg := &dbus.Message{
+		Type: dbus.TypeSignal,
+		Headers: map[dbus.HeaderField]dbus.Variant{
+			dbus.FieldPath:      dbus.MakeVariant(dbus.ObjectPath("/")),
+			dbus.FieldInterface: dbus.MakeVariant(iwdService),
+			dbus.FieldMember:    dbus.MakeVariant("Test"),
+			dbus.FieldSignature: dbus.MakeVariant(sig),
+		},
+		Body: values,
+	}
+	var buf bytes.Buffer
+	if err := msg.EncodeTo(&buf, binary.LittleEndian); err != nil {
+		t.Fatalf("encoding %v: %v", values, err)
+	}
+	return sig, buf.Bytes()
+}
+
+// received returns values as godbus decodes them from a
+// message, e.g. with structs as []interface{}.
+func received(t *testing.T, values ...interface{}) []interface{} {
+	t.Helper()
+	_, wire := encodeBody(t, values)
+	msg, err := dbus.DecodeMessage(bytes.NewReader(wire))
+	if err != nil {
+		t.Fatalf("decoding %v: %v", values, err)
+	}
+	return msg.Body
+}
+
+func TestRecordedMessageJSON(t *testing.T) {
+	type bss struct {
+		Path   dbus.ObjectPath
+		Signal int16
+	}
+	tests := []struct {
+		name   string
+		values []interface{}
+	}{
+		{"basic", []interface{}{
+			true, byte(7), int16(-6000), uint16(11), int32(-1), uint32(2412),
+			int64(-1 << 40), uint64(1 << 63), 0.5, "wlan0", dbus.ObjectPath("/net/connman/iwd/0"),
+			dbus.Signature{},
+		}},
+		{"arrays", []interface{}{
+			[]byte{0, 1, 0xff}, []string{"a", "b"}, []int16{}, [][]byte{{1}, {2, 3}},
+			[]dbus.ObjectPath{"/a", "/b"},
+		}},
+		{"variants", []interface{}{
+			dbus.MakeVariant(uint8(1)), dbus.MakeVariant([]string{"x"}),
+			dbus.MakeVariant(dbus.MakeVariant(int32(2))),
+		}},
+		{"structs", []interface{}{
+			[]bss{{"/net/connman/iwd/0/1/aa", -5000}, {"/net/connman/iwd/0/1/bb", -7000}},
+			bss{"/x", 1},
+		}},
+		{"managed objects", []interface{}{
+			map[dbus.ObjectPath]map[string]map[string]dbus.Variant{
+				"/net/connman/iwd/0/1": {
+					iwdStationIface: {
+						"State":    dbus.MakeVariant("connected"),
+						"Scanning": dbus.MakeVariant(false),
+					},
+					iwdDeviceIface: {},
+				},
+				"/net/connman/iwd": {},
+			},
+		}},
+		{"dictionaries", []interface{}{
+			map[string]dbus.Variant{"Frequency": dbus.MakeVariant(uint32(2412))},
+			map[uint16]string{1: "one", 2: "two"},
+			map[string]dbus.Variant{},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sig, _ := encodeBody(t, test.values)
+			in := RecordedMessage{
+				Path:  "/net/connman/iwd/0/1",
+				Name:  iwdStationIface + ".Test",
+				Args:  received(t, test.values...),
+				Reply: received(t, test.values...),
+			}
+			data, err := json.Marshal(in)
+			if err != nil {
+				t.Fatalf("Marshal() = %v", err)
+			}
+			var out RecordedMessage
+			if err := json.Unmarshal(data, &out); err != nil {
+				t.Fatalf("Unmarshal(%s) = %v", data, err)
+			}
+			if out.Path != in.Path || out.Name != in.Name || out.Signal || out.Error != nil {
+				t.Errorf("Unmarshal(%s) = %+v, want %+v", data, out, in)
+			}
+			for _, values := range [][]interface{}{out.Args, out.Reply} {
+				if got := dbus.SignatureOf(values...); got != sig {
+					t.Errorf("signature after round trip = %s, want %s", got, sig)
+				}
+				// Dictionaries are encoded in random order, so
+				// the messages are compared once decoded.
+				if got, want := received(t, values...), received(t, test.values...); !reflect.DeepEqual(got, want) {
+					t.Errorf("values after round trip are sent as\n%#v\nwant\n%#v", got, want)
+				}
+			}
+			again, err := json.Marshal(out)
+			if err != nil {
+				t.Fatalf("Marshal() after round trip = %v", err)
+			}
+			if !bytes.Equal(again, data) {
+				t.Errorf("JSON after round trip = %s, want %s", again, data)
+			}
+		})
+	}
+}
+
+func TestRecordedSignatureEmptyStructArray(t *testing.T) {
+	// godbus decodes an empty a(on) as []interface{}, which
+	// tells nothing about the elements.
+	if got := recordedSignature([][]interface{}{}); got != "av" {
+		t.Errorf("recordedSignature(empty array of structs) = %q, want av", got)
+	}
+}
+
+func TestRecordReadRecording(t *testing.T) {
+	var buf bytes.Buffer
+	i := &Iwd{}
+	i.Record(&buf)
+	i.recordCall("/net/connman/iwd/0/1", callStationScan, nil, nil,
+		dbus.Error{Name: iwdService + ".Busy", Body: []interface{}{"Operation already in progress"}})
+	i.recordCall("/net/connman/iwd/0/1", callStationScan, nil, nil, context.Canceled)
+	i.recordCall("/net/connman/iwd/0/1", callPropertiesGetAll, []interface{}{iwdStationIface},
+		received(t, map[string]dbus.Variant{"State": dbus.MakeVariant("connected")}), nil)
+	i.recordSignal(&dbus.Signal{
+		Path: "/net/connman/iwd/0/1",
+		Name: dbusPropertiesIface + "." + signalPropertiesChangedMember,
+		Body: received(t, iwdStationIface, map[string]dbus.Variant{"Scanning": dbus.MakeVariant(true)}, []string{}),
+	})
+	i.recordAgentCall("/test/agent", iwdAgentIface+".RequestPassphrase", []interface{}{dbus.ObjectPath("/net/connman/iwd/0/1/cafe_psk")},
+		[]interface{}{"secret"}, nil)
+	i.recordAgentCall("/test/agent", iwdAgentIface+".RequestPassphrase", []interface{}{dbus.ObjectPath("/net/connman/iwd/0/1/cafe_psk")},
+		[]interface{}{""}, dbus.NewError(agentErrorCanceled, []interface{}{"no"}))
+	i.Record(nil)
+	i.recordCall("/", callGetManagedObjects, nil, nil, context.Canceled)
+
+	messages, err := ReadRecording(&buf)
+	if err != nil {
+		t.Fatalf("ReadRecording() = %v", err)
+	}
+	want := []RecordedMessage{
+		{
+			Path:  "/net/connman/iwd/0/1",
+			Name:  callStationScan,
+			Error: &dbus.Error{Name: iwdService + ".Busy", Body: []interface{}{"Operation already in progress"}},
+		},
+		{
+			Path:  "/net/connman/iwd/0/1",
+			Name:  callStationScan,
+			Error: &dbus.Error{Name: "org.freedesktop.DBus.Error.Failed", Body: []interface{}{context.Canceled.Error()}},
+		},
+		{
+			Path:  "/net/connman/iwd/0/1",
+			Name:  callPropertiesGetAll,
+			Args:  []interface{}{iwdStationIface},
+			Reply: []interface{}{map[string]dbus.Variant{"State": dbus.MakeVariant("connected")}},
+		},
+		{
+			Signal: true,
+			Path:   "/net/connman/iwd/0/1",
+			Name:   dbusPropertiesIface + "." + signalPropertiesChangedMember,
+			Args:   []interface{}{iwdStationIface, map[string]dbus.Variant{"Scanning": dbus.MakeVariant(true)}, []string{}},
+		},
+		{
+			Agent: true,
+			Path:  "/test/agent",
+			Name:  iwdAgentIface + ".RequestPassphrase",
+			Args:  []interface{}{dbus.ObjectPath("/net/connman/iwd/0/1/cafe_psk")},
+			Reply: []interface{}{"secret"},
+		},
+		{
+			Agent: true,
+			Path:  "/test/agent",
+			Name:  iwdAgentIface + ".RequestPassphrase",
+			Args:  []interface{}{dbus.ObjectPath("/net/connman/iwd/0/1/cafe_psk")},
+			Error: &dbus.Error{Name: agentErrorCanceled, Body: []interface{}{"no"}},
+		},
+	}
+	if !reflect.DeepEqual(messages, want) {
+		t.Errorf("ReadRecording() =\n%+v\nwant\n%+v", messages, want)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestRecordErr(t *testing.T) {
+	i := &Iwd{}
+	i.Record(failingWriter{})
+	i.recordCall("/", callGetManagedObjects, nil, nil, context.Canceled)
+	i.recordCall("/", callStationScan, nil, nil, context.Canceled)
+	if err := i.RecordErr(); err == nil || err.Error() != "disk full" {
+		t.Errorf("RecordErr() = %v, want the write error", err)
+	}
+	i.Record(&bytes.Buffer{})
+	if err := i.RecordErr(); err != nil {
+		t.Errorf("RecordErr() after Record = %v, want nil", err)
+	}
+}
+
+func TestRecordSignalWithoutHandler(t *testing.T) {
+	var buf bytes.Buffer
+	i := &Iwd{}
+	i.Record(&buf)
+	i.dispatchSignal(&dbus.Signal{
+		Path: "/net/connman/iwd/0/1",
+		Name: dbusPropertiesIface + "." + signalPropertiesChangedMember,
+		Body: received(t, iwdStationIface, map[string]dbus.Variant{"Scanning": dbus.MakeVariant(true)}, []string{}),
+	}, false)
+	messages, err := ReadRecording(&buf)
+	if err != nil {
+		t.Fatalf("ReadRecording() = %v", err)
+	}
+	if len(messages) != 1 || !messages[0].Signal {
+		t.Errorf("ReadRecording() = %+v, want the signal", messages)
+	}
+}
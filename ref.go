@@ -0,0 +1,56 @@
+package iwd
+
+import (
+	"context"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Ref refers to another iwd object by its path.  The object
+// is only built when Resolve is called, so a reference to
+// an object that is broken or gone does not affect the
+// object holding it.
+type Ref[T any] struct {
+	Path        dbus.ObjectPath // Path of the referenced object
+	iwd         *Iwd
+	src         propertySource
+	iface       string
+	constructor func(context.Context, dbus.ObjectPath, *Iwd, propertySource) (*T, error)
+}
+
+func newRef[T any](p dbus.ObjectPath, iface string, i *Iwd, src propertySource,
+	constructor func(context.Context, dbus.ObjectPath, *Iwd, propertySource) (*T, error)) *Ref[T] {
+
+	// Other trees than snapshots are read once to build a
+	// list of objects; keeping them for the references
+	// would return stale objects and keep the whole tree
+	// alive.
+	if g, ok := src.(*graph); ok && !g.snapshot {
+		src = liveSource{i}
+	}
+	return &Ref[T]{Path: p, iwd: i, src: src, iface: iface, constructor: constructor}
+}
+
+// optionalRef returns a reference to the object at the path
+// held by v, or nil if the property is absent.
+func optionalRef[T any](v dbus.Variant, iface string, i *Iwd, src propertySource,
+	constructor func(context.Context, dbus.ObjectPath, *Iwd, propertySource) (*T, error)) *Ref[T] {
+
+	p, ok := v.Value().(dbus.ObjectPath)
+	if !ok || p == "" {
+		return nil
+	}
+	return newRef(p, iface, i, src, constructor)
+}
+
+// Resolve builds the referenced object from iwd as it is
+// now, or, if the object holding the reference was taken
+// from a Manager Snapshot, from that snapshot.
+func (r *Ref[T]) Resolve() (*T, error) {
+	return r.ResolveContext(context.Background())
+}
+
+// ResolveContext is the context-aware variant of Resolve.
+func (r *Ref[T]) ResolveContext(ctx context.Context) (*T, error) {
+	return build(ctx, r.src, r.Path, r.iface, r.iwd, r.constructor)
+}
@@ -0,0 +1,189 @@
+package iwd
+
+import (
+	"context"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	iwdSharedCodeDeviceProvisioningIface = iwdService + ".SharedCodeDeviceProvisioning"
+	iwdSharedCodeAgentIface              = iwdService + ".SharedCodeAgent"
+
+	callSharedCodeDeviceProvisioningConfigureEnrollee = iwdSharedCodeDeviceProvisioningIface + ".ConfigureEnrollee"
+	callSharedCodeDeviceProvisioningStartEnrollee     = iwdSharedCodeDeviceProvisioningIface + ".StartEnrollee"
+	callSharedCodeDeviceProvisioningStartConfigurator = iwdSharedCodeDeviceProvisioningIface + ".StartConfigurator"
+	callSharedCodeDeviceProvisioningStop              = iwdSharedCodeDeviceProvisioningIface + ".Stop"
+
+	sharedCodeAgentErrorCanceled = iwdSharedCodeAgentIface + ".Error.Canceled"
+)
+
+type SharedCodeDeviceProvisioning struct {
+	Path    dbus.ObjectPath  `iwd:"-"` // /net/connman/iwd/{phy0,phy1,...}/{1,2,...}
+	Started bool             // [ro] True if shared code DPP is currently active.
+	Role    ProvisioningRole // [ro] Indicates the DPP role, if started
+	object
+}
+
+// SharedCodeAgent is implemented by applications that
+// supply the shared code to a configurator started with
+// StartConfigurator.  Returning a non-nil error from
+// RequestSharedCode cancels the exchange.
+type SharedCodeAgent interface {
+	// This method gets called when the service daemon
+	// unregisters the agent.
+	Release()
+
+	// This method gets called when a shared code is
+	// required for the enrollee announcing identifier.
+	RequestSharedCode(identifier string) (string, error)
+
+	// This method gets called to indicate that the agent
+	// request failed before a reply was returned.
+	Cancel(reason AgentCancelReason)
+}
+
+// sharedCodeAgentExport adapts a SharedCodeAgent to the
+// method set exported on the D-Bus connection.
+type sharedCodeAgentExport struct {
+	agent SharedCodeAgent
+	path  dbus.ObjectPath
+	iwd   *Iwd
+}
+
+func (a *sharedCodeAgentExport) Release() *dbus.Error {
+	a.iwd.recordAgentCall(a.path, iwdSharedCodeAgentIface+".Release", nil, nil, nil)
+	a.iwd.backend.Export(nil, a.path, iwdSharedCodeAgentIface)
+	a.agent.Release()
+	return nil
+}
+
+func (a *sharedCodeAgentExport) RequestSharedCode(identifier string) (code string, dbusErr *dbus.Error) {
+	defer func() {
+		a.iwd.recordAgentCall(a.path, iwdSharedCodeAgentIface+".RequestSharedCode", []interface{}{identifier},
+			[]interface{}{code}, dbusErr)
+	}()
+	code, err := a.agent.RequestSharedCode(identifier)
+	if err != nil {
+		return "", dbus.NewError(sharedCodeAgentErrorCanceled, []interface{}{err.Error()})
+	}
+	return code, nil
+}
+
+func (a *sharedCodeAgentExport) Cancel(reason string) *dbus.Error {
+	a.iwd.recordAgentCall(a.path, iwdSharedCodeAgentIface+".Cancel", []interface{}{reason}, nil, nil)
+	a.agent.Cancel(AgentCancelReason(reason))
+	return nil
+}
+
+func NewSharedCodeDeviceProvisioning(p dbus.ObjectPath, i *Iwd) (*SharedCodeDeviceProvisioning, error) {
+	return NewSharedCodeDeviceProvisioningContext(context.Background(), p, i)
+}
+
+// NewSharedCodeDeviceProvisioningContext is the context-aware variant of NewSharedCodeDeviceProvisioning.
+func NewSharedCodeDeviceProvisioningContext(ctx context.Context, p dbus.ObjectPath, i *Iwd) (*SharedCodeDeviceProvisioning, error) {
+	return newSharedCodeDeviceProvisioning(ctx, p, i, liveSource{i})
+}
+
+func newSharedCodeDeviceProvisioning(ctx context.Context, p dbus.ObjectPath, i *Iwd, src propertySource) (*SharedCodeDeviceProvisioning, error) {
+	objects, err := src.properties(ctx, p, iwdSharedCodeDeviceProvisioningIface)
+	if err != nil {
+		return nil, err
+	}
+	dpp := &SharedCodeDeviceProvisioning{
+		Path:   p,
+		object: i.object(),
+	}
+	if err := decodeProperties(objects, p, iwdSharedCodeDeviceProvisioningIface, dpp); err != nil {
+		return nil, err
+	}
+	return dpp, nil
+}
+
+// Return the SharedCodeDeviceProvisioning interface of the
+// station.
+func (s *Station) SharedCodeDeviceProvisioning() (*SharedCodeDeviceProvisioning, error) {
+	return s.SharedCodeDeviceProvisioningContext(context.Background())
+}
+
+// SharedCodeDeviceProvisioningContext is the context-aware variant of SharedCodeDeviceProvisioning.
+func (s *Station) SharedCodeDeviceProvisioningContext(ctx context.Context) (*SharedCodeDeviceProvisioning, error) {
+	return NewSharedCodeDeviceProvisioningContext(ctx, s.Path, s.iwd)
+}
+
+func sharedCodeArgs(code, identifier string) map[string]dbus.Variant {
+	args := map[string]dbus.Variant{"Code": dbus.MakeVariant(code)}
+	if identifier != "" {
+		args["Identifier"] = dbus.MakeVariant(identifier)
+	}
+	return args
+}
+
+// Start a shared code configurator using code and,
+// optionally, identifier to configure an enrollee with
+// the currently connected network.  An empty identifier
+// is not sent to iwd.
+func (s *SharedCodeDeviceProvisioning) ConfigureEnrollee(code, identifier string) error {
+	return s.ConfigureEnrolleeContext(context.Background(), code, identifier)
+}
+
+// ConfigureEnrolleeContext is the context-aware variant of ConfigureEnrollee.
+func (s *SharedCodeDeviceProvisioning) ConfigureEnrolleeContext(ctx context.Context, code, identifier string) error {
+	if _, err := s.callMethod(ctx, s.Path, callSharedCodeDeviceProvisioningConfigureEnrollee,
+		sharedCodeArgs(code, identifier)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Start a shared code enrollee using code and,
+// optionally, identifier.  An empty identifier is not
+// sent to iwd.
+func (s *SharedCodeDeviceProvisioning) StartEnrollee(code, identifier string) error {
+	return s.StartEnrolleeContext(context.Background(), code, identifier)
+}
+
+// StartEnrolleeContext is the context-aware variant of StartEnrollee.
+func (s *SharedCodeDeviceProvisioning) StartEnrolleeContext(ctx context.Context, code, identifier string) error {
+	if _, err := s.callMethod(ctx, s.Path, callSharedCodeDeviceProvisioningStartEnrollee,
+		sharedCodeArgs(code, identifier)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Start a shared code configurator which obtains the code
+// from agent.  The agent is exported at path on the
+// library's D-Bus connection and is asked for the code
+// once an enrollee announces its identifier.
+func (s *SharedCodeDeviceProvisioning) StartConfigurator(path dbus.ObjectPath, agent SharedCodeAgent) error {
+	return s.StartConfiguratorContext(context.Background(), path, agent)
+}
+
+// StartConfiguratorContext is the context-aware variant of StartConfigurator.
+func (s *SharedCodeDeviceProvisioning) StartConfiguratorContext(ctx context.Context, path dbus.ObjectPath, agent SharedCodeAgent) error {
+	export := &sharedCodeAgentExport{agent: agent, path: path, iwd: s.iwd}
+	if err := s.iwd.backend.Export(export, path, iwdSharedCodeAgentIface); err != nil {
+		return err
+	}
+	if _, err := s.callMethod(ctx, s.Path, callSharedCodeDeviceProvisioningStartConfigurator,
+		path); err != nil {
+		s.iwd.backend.Export(nil, path, iwdSharedCodeAgentIface)
+		return err
+	}
+	return nil
+}
+
+// Stop a shared code enrollee or configurator that is
+// currently running.
+func (s *SharedCodeDeviceProvisioning) Stop() error {
+	return s.StopContext(context.Background())
+}
+
+// StopContext is the context-aware variant of Stop.
+func (s *SharedCodeDeviceProvisioning) StopContext(ctx context.Context) error {
+	if _, err := s.callMethod(ctx, s.Path, callSharedCodeDeviceProvisioningStop); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package iwd
+
+import (
+	"context"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const signalBuffer = 64
+
+// signalHandler receives the D-Bus signals emitted on path
+// (any path when empty) whose interface and member join
+// to name.
+type signalHandler struct {
+	path   dbus.ObjectPath
+	iface  string
+	member string
+	name   string
+	local  bool // Fed by the daemon watch instead of a match rule
+	handle func(*dbus.Signal)
+}
+
+// addSignalHandler installs a match rule for the signal
+// and calls handle for every matching signal received on
+// the connection.  handle runs on the dispatch goroutine
+// and must not block.
+func (i *Iwd) addSignalHandler(path dbus.ObjectPath, iface, member string,
+	handle func(*dbus.Signal)) (*signalHandler, error) {
+
+	// NameOwnerChanged is synthesized by watchDaemon for
+	// iwd only.
+	local := iface == dbusService && member == signalNameOwnerChanged
+	if !local {
+		if err := i.backend.AddSignalMatch(path, iface, member); err != nil {
+			return nil, err
+		}
+	}
+	h := &signalHandler{
+		path:   path,
+		iface:  iface,
+		member: member,
+		name:   iface + "." + member,
+		local:  local,
+		handle: handle,
+	}
+	i.sigMu.Lock()
+	defer i.sigMu.Unlock()
+	if i.sigHandlers == nil {
+		i.sigHandlers = make(map[*signalHandler]struct{})
+		ch := make(chan *dbus.Signal, signalBuffer)
+		i.backend.Signal(ch)
+		go i.dispatchSignals(ch)
+	}
+	i.sigHandlers[h] = struct{}{}
+	return h, nil
+}
+
+// removeSignalHandler stops delivery to h and removes its
+// match rule.
+func (i *Iwd) removeSignalHandler(h *signalHandler) error {
+	i.sigMu.Lock()
+	delete(i.sigHandlers, h)
+	i.sigMu.Unlock()
+	if h.local {
+		return nil
+	}
+	return i.backend.RemoveSignalMatch(h.path, h.iface, h.member)
+}
+
+func (i *Iwd) dispatchSignals(ch <-chan *dbus.Signal) {
+	for sig := range ch {
+		i.dispatchSignal(sig, false)
+	}
+}
+
+// dispatchSignal calls the handlers matching sig, either
+// the local ones or those installed with a match rule.
+// Signals received from the backend are dispatched, and
+// recorded, only once they are known to come from the
+// running iwd, see admit.
+func (i *Iwd) dispatchSignal(sig *dbus.Signal, local bool) {
+	if !local && !i.admit(sig) {
+		return
+	}
+	i.handleSignal(sig, local)
+}
+
+func (i *Iwd) handleSignal(sig *dbus.Signal, local bool) {
+	i.sigMu.Lock()
+	var handlers []*signalHandler
+	for h := range i.sigHandlers {
+		if h.local == local && (h.path == "" || h.path == sig.Path) && h.name == sig.Name {
+			handlers = append(handlers, h)
+		}
+	}
+	i.sigMu.Unlock()
+	if !local {
+		i.recordSignal(sig)
+	}
+	for _, h := range handlers {
+		h.handle(sig)
+	}
+}
+
+// admit reports whether sig, received from the backend, is
+// to be dispatched now.  Match rules name the well-known
+// iwd service, but a stale signal of an iwd that exited
+// may still be queued, and the bus may deliver signals
+// matched by rules of other users of a shared connection.
+// So signals of another sender than the running iwd are
+// held until its owner changes next: the owner change of a
+// restarted iwd reaches the Iwd apart from the signals and
+// may come after the first ones the new iwd sends.
+// Without a watch on the service the owner is unknown and
+// every signal is admitted.
+func (i *Iwd) admit(sig *dbus.Signal) bool {
+	i.ownerMu.Lock()
+	defer i.ownerMu.Unlock()
+	if !i.watching || sig.Sender == i.owner && !i.replaying {
+		return true
+	}
+	if len(i.held) == signalBuffer {
+		i.held = i.held[1:]
+	}
+	i.held = append(i.held, sig)
+	return false
+}
+
+// replayHeld dispatches the held signals sent by the new
+// owner in the order they were received, and drops the
+// others.  Signals of the owner received meanwhile are
+// held as well, so that they stay in order.
+func (i *Iwd) replayHeld() {
+	for {
+		i.ownerMu.Lock()
+		held, owner := i.held, i.owner
+		i.held = nil
+		i.replaying = len(held) > 0
+		i.ownerMu.Unlock()
+		if len(held) == 0 {
+			return
+		}
+		for _, sig := range held {
+			if sig.Sender == owner {
+				i.handleSignal(sig, false)
+			}
+		}
+	}
+}
+
+// Subscription delivers events decoded from D-Bus signals
+// until it is closed or its context is canceled.  Signals
+// are decoded on a goroutine owned by the subscription, so
+// a slow decoder only delays its own events.  Events are
+// dropped if the Events channel is not drained fast
+// enough.
+type Subscription[T any] struct {
+	Events  <-chan T // Receives the events; closed once the subscription ends
+	events  chan T
+	signals chan *dbus.Signal
+	iwd     *Iwd
+	handler *signalHandler
+	mu      sync.Mutex
+	closed  bool
+	done    chan struct{}
+}
+
+// subscribe delivers the signals matched by path, iface and
+// member to a new Subscription.  Signals for which decode
+// returns false are skipped.
+func subscribe[T any](ctx context.Context, i *Iwd, path dbus.ObjectPath, iface, member string,
+	decode func(*dbus.Signal) (T, bool)) (*Subscription[T], error) {
+
+	events := make(chan T, signalBuffer)
+	s := &Subscription[T]{
+		Events:  events,
+		events:  events,
+		signals: make(chan *dbus.Signal, signalBuffer),
+		iwd:     i,
+		done:    make(chan struct{}),
+	}
+	handler, err := i.addSignalHandler(path, iface, member, s.queue)
+	if err != nil {
+		return nil, err
+	}
+	s.handler = handler
+	go func() {
+		for {
+			select {
+			case sig := <-s.signals:
+				if event, ok := decode(sig); ok {
+					s.deliver(event)
+				}
+			case <-ctx.Done():
+				s.Close()
+				return
+			case <-s.done:
+				return
+			}
+		}
+	}()
+	return s, nil
+}
+
+func (s *Subscription[T]) queue(sig *dbus.Signal) {
+	select {
+	case s.signals <- sig:
+	default:
+	}
+}
+
+func (s *Subscription[T]) deliver(event T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.events <- event:
+	default:
+	}
+}
+
+// Stop receiving events and close the Events channel.
+func (s *Subscription[T]) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.events)
+	close(s.done)
+	s.mu.Unlock()
+	return s.iwd.removeSignalHandler(s.handler)
+}
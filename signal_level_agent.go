@@ -0,0 +1,189 @@
+package iwd
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	iwdSignalLevelAgentIface = iwdService + ".SignalLevelAgent"
+
+	callStationRegisterSignalLevelAgent   = iwdStationIface + ".RegisterSignalLevelAgent"
+	callStationUnregisterSignalLevelAgent = iwdStationIface + ".UnregisterSignalLevelAgent"
+
+	signalLevelAgentBuffer = 16
+
+	// iwd releases the signal level agents of all its
+	// stations when it exits.  A release is taken for the
+	// station going away only if iwd is still running
+	// after releaseGrace.
+	releaseGrace = time.Second
+)
+
+// Index of the RSSI range the current signal strength
+// falls into.  Level 0 means the signal is stronger than
+// the first threshold passed at registration, level N
+// means it is weaker than the N-th threshold.
+type SignalLevel uint8
+
+type SignalLevelAgent struct {
+	Path    dbus.ObjectPath    // Object path the agent is exported at
+	Changes <-chan SignalLevel // Receives the new level every time a threshold is crossed; closed once the agent is released
+	station *Station           // Station the agent is registered with, renewed when iwd restarts
+	levels  []int16
+	iwd     *Iwd
+	events  chan SignalLevel
+	mu      sync.Mutex
+	closed  bool
+}
+
+// signalLevelExport is the method set exported on the
+// D-Bus connection for a SignalLevelAgent.
+type signalLevelExport struct {
+	agent *SignalLevelAgent
+}
+
+func (e *signalLevelExport) Release(device dbus.ObjectPath) *dbus.Error {
+	a := e.agent
+	a.iwd.recordAgentCall(a.Path, iwdSignalLevelAgentIface+".Release", []interface{}{device}, nil, nil)
+	gen := a.iwd.gen.Load()
+	time.AfterFunc(releaseGrace, func() {
+		// If iwd exited meanwhile, the agent is registered
+		// again once it is back.
+		if a.iwd.gen.Load() == gen {
+			a.release()
+		}
+	})
+	return nil
+}
+
+func (e *signalLevelExport) Changed(device dbus.ObjectPath, level uint8) *dbus.Error {
+	e.agent.iwd.recordAgentCall(e.agent.Path, iwdSignalLevelAgentIface+".Changed", []interface{}{device, level}, nil, nil)
+	e.agent.deliver(SignalLevel(level))
+	return nil
+}
+
+// deliver never blocks the D-Bus handler: if the consumer
+// falls behind, the oldest pending level is dropped so the
+// most recent one is always available.
+func (a *SignalLevelAgent) deliver(level SignalLevel) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return
+	}
+	select {
+	case a.events <- level:
+	default:
+		select {
+		case <-a.events:
+		default:
+		}
+		a.events <- level
+	}
+}
+
+func (a *SignalLevelAgent) release() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return
+	}
+	a.closed = true
+	close(a.events)
+	i := a.iwd
+	i.backend.Export(nil, a.Path, iwdSignalLevelAgentIface)
+	i.agentMu.Lock()
+	delete(i.levelAgents, a)
+	i.agentMu.Unlock()
+}
+
+// Register the agent object to receive signal strength
+// level change notifications on the
+// net.connman.iwd.SignalLevelAgent interface.  The levels
+// parameter is a list of signal strength threshold values
+// in dBm, sorted in descending order; the agent receives
+// the index of the range the current RSSI falls into
+// whenever it changes.  When iwd restarts, the agent is
+// registered again with the station at the same path, see
+// SubscribeDaemonEvents.
+func (s *Station) RegisterSignalLevelAgent(path dbus.ObjectPath, levels []int16) (*SignalLevelAgent, error) {
+	return s.RegisterSignalLevelAgentContext(context.Background(), path, levels)
+}
+
+// RegisterSignalLevelAgentContext is the context-aware variant of RegisterSignalLevelAgent.
+func (s *Station) RegisterSignalLevelAgentContext(ctx context.Context, path dbus.ObjectPath, levels []int16) (*SignalLevelAgent, error) {
+	events := make(chan SignalLevel, signalLevelAgentBuffer)
+	agent := &SignalLevelAgent{
+		Path:    path,
+		Changes: events,
+		station: s,
+		levels:  levels,
+		iwd:     s.iwd,
+		events:  events,
+	}
+	if err := s.iwd.backend.Export(&signalLevelExport{agent}, path, iwdSignalLevelAgentIface); err != nil {
+		return nil, err
+	}
+	s.iwd.agentMu.Lock()
+	s.iwd.levelAgents[agent] = struct{}{}
+	s.iwd.agentMu.Unlock()
+	if _, err := s.callMethod(ctx, s.Path, callStationRegisterSignalLevelAgent, path, levels); err != nil {
+		agent.release()
+		return nil, err
+	}
+	return agent, nil
+}
+
+// register registers the agent again with the station at
+// the same path of the iwd of generation gen.
+func (a *SignalLevelAgent) register(ctx context.Context, gen uint64) error {
+	a.mu.Lock()
+	closed := a.closed
+	path := a.station.Path
+	a.mu.Unlock()
+	if closed {
+		return nil
+	}
+	s, err := NewStationContext(ctx, path, a.iwd)
+	if err != nil {
+		return err
+	}
+	if s.gen != gen {
+		return errVanished
+	}
+	if _, err := s.callMethod(ctx, s.Path, callStationRegisterSignalLevelAgent, a.Path, a.levels); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	a.station = s
+	a.mu.Unlock()
+	return nil
+}
+
+// Unregister the agent from the station, stop exporting
+// it and close the Changes channel.  The agent is closed
+// even if unregistering it fails, e.g. with ErrStale while
+// iwd restarts.
+func (a *SignalLevelAgent) Close() error {
+	return a.CloseContext(context.Background())
+}
+
+// CloseContext is the context-aware variant of Close.
+func (a *SignalLevelAgent) CloseContext(ctx context.Context) error {
+	a.mu.Lock()
+	closed := a.closed
+	station := a.station
+	a.mu.Unlock()
+	if closed {
+		return nil
+	}
+	defer a.release()
+	if _, err := station.callMethod(ctx, station.Path, callStationUnregisterSignalLevelAgent, a.Path); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package iwd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/shtirlic/go-iwd/utils"
+)
+
+// ErrUnknownObject is returned when an object or one of its
+// interfaces is not known to iwd (anymore).
+var ErrUnknownObject = errors.New("iwd: unknown object")
+
+// propertySource provides the properties the constructors
+// decode objects from.
+type propertySource interface {
+	properties(ctx context.Context, p dbus.ObjectPath, iface string) (utils.DBusMapVariant, error)
+}
+
+// liveSource queries iwd for the properties of every object.
+type liveSource struct {
+	iwd *Iwd
+}
+
+func (s liveSource) properties(ctx context.Context, p dbus.ObjectPath, iface string) (utils.DBusMapVariant, error) {
+	objects, err := s.iwd.getAllProperties(ctx, p, iface)
+	var dbusErr dbus.Error
+	if errors.As(err, &dbusErr) {
+		switch dbusErr.Name {
+		// GetAll has no other argument than the interface,
+		// so InvalidArgs means iwd does not know it.
+		case "org.freedesktop.DBus.Error.UnknownObject", "org.freedesktop.DBus.Error.NoSuchObject",
+			"org.freedesktop.DBus.Error.UnknownInterface", "org.freedesktop.DBus.Error.UnknownMethod",
+			"org.freedesktop.DBus.Error.InvalidArgs":
+			return nil, fmt.Errorf("%w: %s %s: %w", ErrUnknownObject, p, iface, err)
+		}
+	}
+	return objects, err
+}
+
+// graph reads the properties from a single GetManagedObjects
+// result.  Every object is built once per graph, so objects
+// referring to the same path share one instance.
+type graph struct {
+	objects  utils.DBusRetValues
+	fallback propertySource // Asked for objects missing from objects, if set
+	snapshot bool           // Whether Refs resolve against the graph rather than iwd, see newRef
+	mu       sync.Mutex
+	built    map[graphKey]interface{}
+}
+
+type graphKey struct {
+	path  dbus.ObjectPath
+	iface string
+}
+
+func newGraph(objects utils.DBusRetValues) *graph {
+	return &graph{objects: objects, built: make(map[graphKey]interface{})}
+}
+
+// loadGraph fetches the whole object tree of iwd in one
+// round trip.
+func loadGraph(ctx context.Context, i *Iwd) (*graph, error) {
+	objects, err := i.getManagedObjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newGraph(objects), nil
+}
+
+// signalGraph builds the objects announced by an
+// InterfacesAdded signal from the properties it carries.
+// Objects they refer to are not part of the signal and are
+// queried from iwd when resolved.
+func signalGraph(i *Iwd, p dbus.ObjectPath, ifaces map[string]map[string]dbus.Variant) *graph {
+	g := newGraph(utils.DBusRetValues{p: ifaces})
+	g.fallback = liveSource{i}
+	return g
+}
+
+func (g *graph) properties(ctx context.Context, p dbus.ObjectPath, iface string) (utils.DBusMapVariant, error) {
+	objects, ok := g.objects[p][iface]
+	if !ok && g.fallback != nil {
+		return g.fallback.properties(ctx, p, iface)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %s %s", ErrUnknownObject, p, iface)
+	}
+	return objects, nil
+}
+
+// paths returns the sorted paths of the objects that
+// implement iface.
+func (g *graph) paths(iface string) []dbus.ObjectPath {
+	var paths []dbus.ObjectPath
+	for p, ifaces := range g.objects {
+		if _, ok := ifaces[iface]; ok {
+			paths = append(paths, p)
+		}
+	}
+	sort.Slice(paths, func(a, b int) bool { return paths[a] < paths[b] })
+	return paths
+}
+
+// build returns the object implementing iface at p.  When
+// src is a graph, the object is only constructed the first
+// time it is asked for.
+func build[T any](ctx context.Context, src propertySource, p dbus.ObjectPath, iface string, i *Iwd,
+	constructor func(context.Context, dbus.ObjectPath, *Iwd, propertySource) (*T, error)) (*T, error) {
+
+	g, ok := src.(*graph)
+	if !ok {
+		return constructor(ctx, p, i, src)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	key := graphKey{p, iface}
+	if obj, ok := g.built[key]; ok {
+		return obj.(*T), nil
+	}
+	obj, err := constructor(ctx, p, i, src)
+	if err != nil {
+		return nil, err
+	}
+	g.built[key] = obj
+	return obj, nil
+}
+
+// buildAll builds every object implementing iface in g.
+func buildAll[T any](ctx context.Context, g *graph, iface string, i *Iwd,
+	constructor func(context.Context, dbus.ObjectPath, *Iwd, propertySource) (*T, error)) ([]*T, error) {
+
+	var result []*T
+	for _, p := range g.paths(iface) {
+		obj, err := build(ctx, g, p, iface, i, constructor)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, obj)
+	}
+	return result, nil
+}
+
+// decodeProperties fills the struct pointed to by out from
+// the properties of iface on the object at p.
+func decodeProperties(objects utils.DBusMapVariant, p dbus.ObjectPath, iface string, out interface{}) error {
+	if err := utils.Decode(objects, out); err != nil {
+		return fmt.Errorf("iwd: %s %s: %w", p, iface, err)
+	}
+	return nil
+}
@@ -1,6 +1,9 @@
 package iwd
 
 import (
+	"context"
+	"errors"
+
 	"github.com/godbus/dbus/v5"
 	"github.com/shtirlic/go-iwd/utils"
 )
@@ -34,17 +37,25 @@ type NetworkWithSignal struct {
 	SignalStrength SignalStrength
 }
 
+// pathWithSignal is an entry of the a(on) lists returned by
+// GetOrderedNetworks and GetPeers.
+type pathWithSignal struct {
+	Path   dbus.ObjectPath
+	Signal SignalStrength
+}
+
 // Network's maximum signal strength expressed
 // in 100 * dBm.  The value is the range of 0
 // (strongest signal) to -10000 (weakest signal)
 type SignalStrength int16
 
 type Station struct {
-	Path             dbus.ObjectPath // /net/connman/iwd/{phy0,phy1,...}/{1,2,...}
-	ConnectedNetwork *Network        // [ro] Reflects the object representing the network the device is currently connected to or to which a connection is in progress.
-	Scanning         bool            // [ro] Reflects whether the station is currently scanning for networks.
-	State            ConnectionState // [ro] Reflects the general network connection state.
-	iwd              *Iwd
+	Path                 dbus.ObjectPath       `iwd:"-"` // /net/connman/iwd/{phy0,phy1,...}/{1,2,...}
+	ConnectedNetwork     *Ref[Network]         `iwd:"-"` // [ro] Reflects the object representing the network the device is currently connected to or to which a connection is in progress.
+	ConnectedAccessPoint *Ref[BasicServiceSet] `iwd:"-"` // [ro] Reflects the object representing the BSS the device is currently connected to or to which a connection is in progress.
+	Scanning             bool                  // [ro] Reflects whether the station is currently scanning for networks.
+	State                ConnectionState       // [ro] Reflects the general network connection state.
+	object
 }
 
 type StationDiagnosticInfo struct {
@@ -66,28 +77,57 @@ type StationDiagnosticInfo struct {
 }
 
 func NewStation(p dbus.ObjectPath, i *Iwd) (*Station, error) {
-	objects, err := utils.GetAllProperties(i.conn, iwdService, p, iwdStationIface)
+	return NewStationContext(context.Background(), p, i)
+}
+
+// NewStationContext is the context-aware variant of NewStation.
+func NewStationContext(ctx context.Context, p dbus.ObjectPath, i *Iwd) (*Station, error) {
+	return newStation(ctx, p, i, liveSource{i})
+}
+
+func newStation(ctx context.Context, p dbus.ObjectPath, i *Iwd, src propertySource) (*Station, error) {
+	objects, err := src.properties(ctx, p, iwdStationIface)
 	if err != nil {
 		return nil, err
 	}
-	var cnetwork *Network
-	if cnetworkValue := objects["ConnectedNetwork"].Value(); cnetworkValue != nil {
-		if cnetwork, err = NewNetwork(cnetworkValue.(dbus.ObjectPath), i); err != nil {
-			return nil, err
-		}
+	station := &Station{
+		Path:                 p,
+		ConnectedNetwork:     optionalRef(objects["ConnectedNetwork"], iwdNetworkIface, i, src, newNetwork),
+		ConnectedAccessPoint: optionalRef(objects["ConnectedAccessPoint"], iwdBasicServiceSetIface, i, src, newBasicServiceSet),
+		object:               i.object(),
 	}
-	return &Station{
-		Path:             p,
-		ConnectedNetwork: cnetwork,
-		Scanning:         objects["Scanning"].Value().(bool),
-		State:            ConnectionState(objects["State"].Value().(string)),
-		iwd:              i,
-	}, nil
+	if err := decodeProperties(objects, p, iwdStationIface, station); err != nil {
+		return nil, err
+	}
+	return station, nil
+}
+
+// Reload the properties of the station from iwd in place.
+// The returned error matches ErrUnknownObject if the
+// station has disappeared since.  Refresh writes the
+// fields, so the station must not be used concurrently.
+func (s *Station) Refresh() error {
+	return s.RefreshContext(context.Background())
+}
+
+// RefreshContext is the context-aware variant of Refresh.
+func (s *Station) RefreshContext(ctx context.Context) error {
+	fresh, err := NewStationContext(ctx, s.Path, s.iwd)
+	if err != nil {
+		return err
+	}
+	*s = *fresh
+	return nil
 }
 
 // Schedule a network scan.
 func (s *Station) Scan() error {
-	if _, err := s.iwd.CallServiceMethod(s.Path, callStationScan); err != nil {
+	return s.ScanContext(context.Background())
+}
+
+// ScanContext is the context-aware variant of Scan.
+func (s *Station) ScanContext(ctx context.Context) error {
+	if _, err := s.callMethod(ctx, s.Path, callStationScan); err != nil {
 		return err
 	}
 	return nil
@@ -97,7 +137,12 @@ func (s *Station) Scan() error {
 // iwd from trying to autoconnect to any other network
 // with this device.
 func (s *Station) Disconnect() error {
-	if _, err := s.iwd.CallServiceMethod(s.Path, callStationDisconnect); err != nil {
+	return s.DisconnectContext(context.Background())
+}
+
+// DisconnectContext is the context-aware variant of Disconnect.
+func (s *Station) DisconnectContext(ctx context.Context) error {
+	if _, err := s.callMethod(ctx, s.Path, callStationDisconnect); err != nil {
 		return err
 	}
 	return nil
@@ -114,23 +159,37 @@ func (s *Station) Disconnect() error {
 // groups the maximum relative signal-strength is the
 // main sorting factor.
 func (s *Station) GetOrderedNetworks() ([]NetworkWithSignal, error) {
-	call, err := s.iwd.CallServiceMethod(s.Path, callStationGetOrderedNetworks)
+	return s.GetOrderedNetworksContext(context.Background())
+}
+
+// GetOrderedNetworksContext is the context-aware variant of GetOrderedNetworks.
+func (s *Station) GetOrderedNetworksContext(ctx context.Context) ([]NetworkWithSignal, error) {
+	call, err := s.callMethod(ctx, s.Path, callStationGetOrderedNetworks)
 	if err != nil {
 		return nil, err
 	}
-	var objects utils.DBusArrTupleVariant
+	// Build all entries from one copy of the object tree
+	// rather than querying every one of them.
+	g, err := loadGraph(ctx, s.iwd)
+	if err != nil {
+		return nil, err
+	}
+	var objects []pathWithSignal
 	var oNets []NetworkWithSignal
 	if err = call.Store(&objects); err != nil {
 		return nil, err
 	}
-	for _, i := range objects {
-		p := i[0].Value().(dbus.ObjectPath)
-		ss := SignalStrength(i[1].Value().(int16))
-		if network, err := NewNetwork(p, s.iwd); err == nil {
-			oNets = append(oNets, NetworkWithSignal{network, ss})
-		} else {
+	for _, o := range objects {
+		network, err := build(ctx, g, o.Path, iwdNetworkIface, s.iwd, newNetwork)
+		if errors.Is(err, ErrUnknownObject) {
+			// Networks that vanished between the two calls are
+			// left out.
+			continue
+		}
+		if err != nil {
 			return nil, err
 		}
+		oNets = append(oNets, NetworkWithSignal{network, o.Signal})
 	}
 	return oNets, nil
 }
@@ -156,7 +215,12 @@ func (s *Station) GetOrderedNetworks() ([]NetworkWithSignal, error) {
 // a hidden network.  For all future connections the
 // regular Network.Connect() API should be used.
 func (s *Station) ConnectHiddenNetwork(ssid string) error {
-	if _, err := s.iwd.CallServiceMethod(s.Path, callStationConnectHiddenNetwork,
+	return s.ConnectHiddenNetworkContext(context.Background(), ssid)
+}
+
+// ConnectHiddenNetworkContext is the context-aware variant of ConnectHiddenNetwork.
+func (s *Station) ConnectHiddenNetworkContext(ctx context.Context, ssid string) error {
+	if _, err := s.callMethod(ctx, s.Path, callStationConnectHiddenNetwork,
 		ssid); err != nil {
 		return err
 	}
@@ -172,7 +236,12 @@ func (s *Station) ConnectHiddenNetwork(ssid string) error {
 // properties. The values in the dictionary may come and
 // go depending on the state of IWD.
 func (s *Station) GetDiagnostics() (*StationDiagnosticInfo, error) {
-	call, err := s.iwd.CallServiceMethod(s.Path, callStationDiagnosticGetDiagnostics)
+	return s.GetDiagnosticsContext(context.Background())
+}
+
+// GetDiagnosticsContext is the context-aware variant of GetDiagnostics.
+func (s *Station) GetDiagnosticsContext(ctx context.Context) (*StationDiagnosticInfo, error) {
+	call, err := s.callMethod(ctx, s.Path, callStationDiagnosticGetDiagnostics)
 	if err != nil {
 		return nil, err
 	}
@@ -181,7 +250,7 @@ func (s *Station) GetDiagnostics() (*StationDiagnosticInfo, error) {
 		return nil, err
 	}
 	var diaginfo StationDiagnosticInfo
-	if err := utils.Transcode(objects, &diaginfo); err != nil {
+	if err := utils.Decode(objects, &diaginfo); err != nil {
 		return nil, err
 	}
 	return &diaginfo, nil
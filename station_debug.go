@@ -0,0 +1,193 @@
+package iwd
+
+import (
+	"context"
+	"net"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/shtirlic/go-iwd/utils"
+)
+
+const (
+	iwdStationDebugIface = iwdService + ".StationDebug"
+
+	callStationDebugConnectBssid = iwdStationDebugIface + ".ConnectBssid"
+	callStationDebugRoam         = iwdStationDebugIface + ".Roam"
+	callStationDebugGetNetworks  = iwdStationDebugIface + ".GetNetworks"
+
+	signalStationDebugEvent = "Event"
+)
+
+type StationDebug struct {
+	Path        dbus.ObjectPath `iwd:"-"` // /net/connman/iwd/{phy0,phy1,...}/{1,2,...}
+	AutoConnect bool            // [rw] Whether iwd autoconnect is enabled for the station
+	object
+}
+
+type BSSDebugInfo struct {
+	Address   string // MAC address of the BSS.
+	Frequency int    // Frequency the BSS operates on.
+	RSSI      int    // The RSSI of the BSS in 100 * dBm.
+	Rank      int    // Rank of the BSS as calculated by iwd.
+	MDE       []byte // Mobility Domain element of the BSS, if advertised.
+}
+
+// Name of a debug event, e.g. "roam-scan-triggered".
+// iwd may emit others than the ones listed here.
+type StationDebugEventName string
+
+const (
+	RoamScanTriggeredEvent   StationDebugEventName = "roam-scan-triggered"
+	NoRoamCandidatesEvent    StationDebugEventName = "no-roam-candidates"
+	FTRoamEvent              StationDebugEventName = "ft-roam"
+	ReassocRoamEvent         StationDebugEventName = "reassoc-roam"
+	FTFallbackToReassocEvent StationDebugEventName = "ft-fallback-to-reassoc"
+)
+
+// Debug event emitted by iwd, e.g. while roaming.
+type StationDebugEvent struct {
+	Name    StationDebugEventName
+	Address string        // Hardware address of the BSS the event is about, e.g. the roam target, if iwd names one
+	Data    []interface{} // Arguments not decoded into the fields above
+}
+
+func NewStationDebug(p dbus.ObjectPath, i *Iwd) (*StationDebug, error) {
+	return NewStationDebugContext(context.Background(), p, i)
+}
+
+// NewStationDebugContext is the context-aware variant of NewStationDebug.
+func NewStationDebugContext(ctx context.Context, p dbus.ObjectPath, i *Iwd) (*StationDebug, error) {
+	return newStationDebug(ctx, p, i, liveSource{i})
+}
+
+func newStationDebug(ctx context.Context, p dbus.ObjectPath, i *Iwd, src propertySource) (*StationDebug, error) {
+	objects, err := src.properties(ctx, p, iwdStationDebugIface)
+	if err != nil {
+		return nil, err
+	}
+	debug := &StationDebug{
+		Path:   p,
+		object: i.object(),
+	}
+	if err := decodeProperties(objects, p, iwdStationDebugIface, debug); err != nil {
+		return nil, err
+	}
+	return debug, nil
+}
+
+// Return the StationDebug interface of the station.
+func (s *Station) Debug() (*StationDebug, error) {
+	return s.DebugContext(context.Background())
+}
+
+// DebugContext is the context-aware variant of Debug.
+func (s *Station) DebugContext(ctx context.Context) (*StationDebug, error) {
+	return NewStationDebugContext(ctx, s.Path, s.iwd)
+}
+
+// Connect to the BSS with the hardware address mac,
+// given in the XX:XX:XX:XX:XX:XX format.  The BSS must
+// be part of a network found in the most recent scan.
+func (d *StationDebug) ConnectBssid(mac string) error {
+	return d.ConnectBssidContext(context.Background(), mac)
+}
+
+// ConnectBssidContext is the context-aware variant of ConnectBssid.
+func (d *StationDebug) ConnectBssidContext(ctx context.Context, mac string) error {
+	addr, err := net.ParseMAC(mac)
+	if err != nil {
+		return err
+	}
+	if _, err := d.callMethod(ctx, d.Path, callStationDebugConnectBssid, []byte(addr)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Roam to the BSS with the hardware address mac, given
+// in the XX:XX:XX:XX:XX:XX format.  The station must be
+// connected and the BSS must belong to the connected
+// network.
+func (d *StationDebug) Roam(mac string) error {
+	return d.RoamContext(context.Background(), mac)
+}
+
+// RoamContext is the context-aware variant of Roam.
+func (d *StationDebug) RoamContext(ctx context.Context, mac string) error {
+	addr, err := net.ParseMAC(mac)
+	if err != nil {
+		return err
+	}
+	if _, err := d.callMethod(ctx, d.Path, callStationDebugRoam, []byte(addr)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Return every network found in the most recent scan,
+// keyed by network object path, together with the BSS
+// entries seen for it.
+func (d *StationDebug) GetNetworks() (map[dbus.ObjectPath][]BSSDebugInfo, error) {
+	return d.GetNetworksContext(context.Background())
+}
+
+// GetNetworksContext is the context-aware variant of GetNetworks.
+func (d *StationDebug) GetNetworksContext(ctx context.Context) (map[dbus.ObjectPath][]BSSDebugInfo, error) {
+	call, err := d.callMethod(ctx, d.Path, callStationDebugGetNetworks)
+	if err != nil {
+		return nil, err
+	}
+	var objects map[dbus.ObjectPath][]utils.DBusMapVariant
+	if err := call.Store(&objects); err != nil {
+		return nil, err
+	}
+	networks := make(map[dbus.ObjectPath][]BSSDebugInfo, len(objects))
+	for p, bssList := range objects {
+		for _, o := range bssList {
+			var bss BSSDebugInfo
+			if err := utils.Decode(o, &bss); err != nil {
+				return nil, err
+			}
+			networks[p] = append(networks[p], bss)
+		}
+	}
+	return networks, nil
+}
+
+// Subscribe to the Event signal of the station.
+func (d *StationDebug) SubscribeEvents(ctx context.Context) (*Subscription[StationDebugEvent], error) {
+	return subscribe(ctx, d.iwd, d.Path, iwdStationDebugIface, signalStationDebugEvent, decodeStationDebugEvent)
+}
+
+func decodeStationDebugEvent(sig *dbus.Signal) (StationDebugEvent, bool) {
+	if len(sig.Body) < 2 {
+		return StationDebugEvent{}, false
+	}
+	name, _ := sig.Body[0].(string)
+	variants, _ := sig.Body[1].([]dbus.Variant)
+	event := StationDebugEvent{Name: StationDebugEventName(name)}
+	for _, v := range variants {
+		// BSSs are named by their hardware address, as in
+		// ConnectBssid and Roam.
+		if addr, ok := v.Value().([]byte); ok && len(addr) == 6 && event.Address == "" {
+			event.Address = net.HardwareAddr(addr).String()
+			continue
+		}
+		event.Data = append(event.Data, v.Value())
+	}
+	return event, true
+}
+
+// Enable or disable iwd autoconnect for the station.
+func (d *StationDebug) SetAutoConnect(autoConnect bool) error {
+	return d.SetAutoConnectContext(context.Background(), autoConnect)
+}
+
+// SetAutoConnectContext is the context-aware variant of SetAutoConnect.
+func (d *StationDebug) SetAutoConnectContext(ctx context.Context, autoConnect bool) error {
+	if err := d.setProperty(ctx, d.Path, iwdStationDebugIface, "AutoConnect", autoConnect); err != nil {
+		return err
+	}
+	d.AutoConnect = autoConnect
+	return nil
+}
@@ -0,0 +1,175 @@
+package utils
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// DecodeTag is the struct tag Decode reads property names
+// from.
+const DecodeTag = "iwd"
+
+var (
+	variantType    = reflect.TypeOf(dbus.Variant{})
+	variantMapType = reflect.TypeOf(map[string]dbus.Variant{})
+)
+
+// DecodeError reports a property that could not be stored
+// in its struct field.
+type DecodeError struct {
+	Property string       // Property name, with the names of enclosing dictionaries and list indexes
+	Value    interface{}  // Value as received from D-Bus
+	Type     reflect.Type // Type of the field
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("property %s: cannot decode %T into %s", e.Property, e.Value, e.Type)
+}
+
+// Decode fills the struct pointed to by out from a
+// dictionary of properties, as returned by GetAll or found
+// in method replies.
+//
+// Every exported field is decoded from the property of the
+// same name, or the name given in an `iwd:"Name"` tag;
+// fields tagged `iwd:"-"` are skipped.  Missing properties
+// leave the field unchanged, and properties without a field
+// are ignored, since iwd may add or omit them between
+// versions.  Values are stored if they are assignable or
+// convertible to the field type: named types like
+// `type State string` accept their underlying type and
+// integer fields accept any integer value that fits.
+// Slices are decoded element by element, dictionaries into
+// nested structs or maps, and pointer fields are allocated
+// if the property is present.  A value of a different kind
+// yields a *DecodeError instead of a panic.
+func Decode(in map[string]dbus.Variant, out interface{}) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("utils: Decode needs a non-nil struct pointer, got %T", out)
+	}
+	return decodeStruct(in, v.Elem(), "")
+}
+
+func decodeStruct(in map[string]dbus.Variant, out reflect.Value, prefix string) error {
+	t := out.Type()
+	for n := 0; n < t.NumField(); n++ {
+		field := t.Field(n)
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		name := field.Name
+		if tag, ok := field.Tag.Lookup(DecodeTag); ok {
+			if tag == "-" {
+				continue
+			}
+			name = tag
+		}
+		variant, ok := in[name]
+		if !ok {
+			continue
+		}
+		if err := decodeValue(variant, out.Field(n), prefix+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeValue(in interface{}, out reflect.Value, name string) error {
+	if variant, ok := in.(dbus.Variant); ok && out.Type() != variantType {
+		in = variant.Value()
+	}
+	mismatch := &DecodeError{Property: name, Value: in, Type: out.Type()}
+	if in == nil {
+		return mismatch
+	}
+	v := reflect.ValueOf(in)
+	switch {
+	case v.Type().AssignableTo(out.Type()):
+		out.Set(v)
+		return nil
+	case out.Kind() == reflect.Interface:
+		// Only reached if the value does not implement
+		// the interface.
+		return mismatch
+	case out.Kind() == reflect.Pointer:
+		elem := reflect.New(out.Type().Elem())
+		if err := decodeValue(in, elem.Elem(), name); err != nil {
+			return err
+		}
+		out.Set(elem)
+		return nil
+	case out.Kind() == reflect.Struct && v.Type() == variantMapType:
+		return decodeStruct(in.(map[string]dbus.Variant), out, name+".")
+	case out.Kind() == reflect.Slice && v.Kind() == reflect.Slice:
+		s := reflect.MakeSlice(out.Type(), v.Len(), v.Len())
+		for n := 0; n < v.Len(); n++ {
+			if err := decodeValue(v.Index(n).Interface(), s.Index(n), fmt.Sprintf("%s[%d]", name, n)); err != nil {
+				return err
+			}
+		}
+		out.Set(s)
+		return nil
+	case out.Kind() == reflect.Map && v.Kind() == reflect.Map:
+		m := reflect.MakeMapWithSize(out.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key := reflect.New(out.Type().Key()).Elem()
+			if err := decodeValue(iter.Key().Interface(), key, name); err != nil {
+				return err
+			}
+			elem := reflect.New(out.Type().Elem()).Elem()
+			if err := decodeValue(iter.Value().Interface(), elem, fmt.Sprintf("%s[%v]", name, iter.Key())); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, elem)
+		}
+		out.Set(m)
+		return nil
+	case isInt(v.Kind()) && isInt(out.Kind()):
+		return decodeInt(v, out, mismatch)
+	case v.Kind() == out.Kind() && v.Type().ConvertibleTo(out.Type()):
+		out.Set(v.Convert(out.Type()))
+		return nil
+	}
+	return mismatch
+}
+
+// decodeInt stores any integer value that fits into an
+// integer field, e.g. an int16 RSSI into an int.
+func decodeInt(v, out reflect.Value, mismatch error) error {
+	switch {
+	case isSigned(v.Kind()) && isSigned(out.Kind()):
+		if out.OverflowInt(v.Int()) {
+			return mismatch
+		}
+		out.SetInt(v.Int())
+	case isSigned(v.Kind()):
+		if v.Int() < 0 || out.OverflowUint(uint64(v.Int())) {
+			return mismatch
+		}
+		out.SetUint(uint64(v.Int()))
+	case isSigned(out.Kind()):
+		if v.Uint() > 1<<63-1 || out.OverflowInt(int64(v.Uint())) {
+			return mismatch
+		}
+		out.SetInt(int64(v.Uint()))
+	default:
+		if out.OverflowUint(v.Uint()) {
+			return mismatch
+		}
+		out.SetUint(v.Uint())
+	}
+	return nil
+}
+
+func isInt(k reflect.Kind) bool {
+	return isSigned(k) || k >= reflect.Uint && k <= reflect.Uintptr
+}
+
+func isSigned(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
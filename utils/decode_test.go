@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+type testState string
+
+type testNested struct {
+	Frequency uint32
+}
+
+type testProps struct {
+	Name      string
+	State     testState
+	Signal    int
+	Small     int8
+	Channel   uint8
+	Renamed   string `iwd:"Other"`
+	Skipped   string `iwd:"-"`
+	Addresses []string
+	Nested    testNested
+	Optional  *bool
+	Extra     map[string]int32
+	Raw       dbus.Variant
+	Kept      string
+	unchanged string
+}
+
+func TestDecode(t *testing.T) {
+	in := map[string]dbus.Variant{
+		"Name":      dbus.MakeVariant("wlan0"),
+		"State":     dbus.MakeVariant("connected"),
+		"Signal":    dbus.MakeVariant(int16(-6000)),
+		"Small":     dbus.MakeVariant(int32(-12)),
+		"Channel":   dbus.MakeVariant(uint16(11)),
+		"Other":     dbus.MakeVariant("tagged"),
+		"Skipped":   dbus.MakeVariant("ignored"),
+		"Addresses": dbus.MakeVariant([]string{"a", "b"}),
+		"Nested":    dbus.MakeVariant(map[string]dbus.Variant{"Frequency": dbus.MakeVariant(uint32(2412))}),
+		"Optional":  dbus.MakeVariant(true),
+		"Extra":     dbus.MakeVariant(map[string]int16{"x": 1}),
+		"Raw":       dbus.MakeVariant(uint8(7)),
+		"Unknown":   dbus.MakeVariant("no field"),
+	}
+	out := testProps{Skipped: "kept", Kept: "kept", unchanged: "kept"}
+	if err := Decode(in, &out); err != nil {
+		t.Fatalf("Decode() = %v", err)
+	}
+	optional := true
+	want := testProps{
+		Name:      "wlan0",
+		State:     "connected",
+		Signal:    -6000,
+		Small:     -12,
+		Channel:   11,
+		Renamed:   "tagged",
+		Skipped:   "kept",
+		Addresses: []string{"a", "b"},
+		Nested:    testNested{Frequency: 2412},
+		Optional:  &optional,
+		Extra:     map[string]int32{"x": 1},
+		Raw:       dbus.MakeVariant(uint8(7)),
+		Kept:      "kept",
+		unchanged: "kept",
+	}
+	if !reflect.DeepEqual(out, want) {
+		t.Errorf("Decode() stored\n%+v, want\n%+v", out, want)
+	}
+}
+
+func TestDecodeMissing(t *testing.T) {
+	out := testProps{Name: "before"}
+	if err := Decode(map[string]dbus.Variant{}, &out); err != nil {
+		t.Fatalf("Decode() = %v", err)
+	}
+	if out.Name != "before" || out.Optional != nil || out.Addresses != nil {
+		t.Errorf("Decode() changed fields of missing properties: %+v", out)
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       map[string]dbus.Variant
+		property string
+	}{
+		{"type mismatch", map[string]dbus.Variant{"Name": dbus.MakeVariant(uint32(1))}, "Name"},
+		{"string into int", map[string]dbus.Variant{"Signal": dbus.MakeVariant("strong")}, "Signal"},
+		{"signed overflow", map[string]dbus.Variant{"Small": dbus.MakeVariant(int32(200))}, "Small"},
+		{"negative into unsigned", map[string]dbus.Variant{"Channel": dbus.MakeVariant(int16(-1))}, "Channel"},
+		{"unsigned overflow", map[string]dbus.Variant{"Channel": dbus.MakeVariant(uint16(256))}, "Channel"},
+		{"list element", map[string]dbus.Variant{"Addresses": dbus.MakeVariant([]int32{1})}, "Addresses[0]"},
+		{"nested", map[string]dbus.Variant{
+			"Nested": dbus.MakeVariant(map[string]dbus.Variant{"Frequency": dbus.MakeVariant("2.4")}),
+		}, "Nested.Frequency"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out testProps
+			err := Decode(test.in, &out)
+			var decodeErr *DecodeError
+			if !errors.As(err, &decodeErr) {
+				t.Fatalf("Decode() = %v, want a *DecodeError", err)
+			}
+			if decodeErr.Property != test.property {
+				t.Errorf("DecodeError.Property = %q, want %q", decodeErr.Property, test.property)
+			}
+		})
+	}
+}
+
+func TestDecodeNotStruct(t *testing.T) {
+	var s string
+	for _, out := range []interface{}{nil, testProps{}, &s, (*testProps)(nil)} {
+		if err := Decode(nil, out); err == nil {
+			t.Errorf("Decode(%T) = nil, want an error", out)
+		}
+	}
+}
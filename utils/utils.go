@@ -2,6 +2,7 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 
@@ -11,15 +12,20 @@ import (
 const (
 	callGetManagedObjects = "org.freedesktop.DBus.ObjectManager.GetManagedObjects"
 	callPropertiesGetAll  = "org.freedesktop.DBus.Properties.GetAll"
+	callPropertiesSet     = "org.freedesktop.DBus.Properties.Set"
 )
 
 type ObjectConstructor[T any, V any] func(dbus.ObjectPath, V) (*T, error)
+type ObjectConstructorContext[T any, V any] func(context.Context, dbus.ObjectPath, V) (*T, error)
 
 type DBusRetValues map[dbus.ObjectPath]map[string]map[string]dbus.Variant
 type DBusMapVariant map[string]dbus.Variant
 type DBusArrTupleVariant [][]dbus.Variant
 
 // Transcode converts a map of dbus.Variant values to JSON and decodes it into an output interface.
+//
+// Deprecated: Use Decode, which converts the values directly
+// and reports type mismatches.
 func Transcode(in map[string]dbus.Variant, out interface{}) error {
 	tmp := make(map[string]interface{})
 	for k, v := range in {
@@ -38,7 +44,16 @@ func Transcode(in map[string]dbus.Variant, out interface{}) error {
 func GetObjectsByInterface[T any, V any](conn *dbus.Conn, service string, interfaceName string,
 	constructor ObjectConstructor[T, V], i V) ([]*T, error) {
 
-	objects, err := GetManagedObjects(conn, service)
+	return GetObjectsByInterfaceContext(context.Background(), conn, service, interfaceName,
+		func(_ context.Context, p dbus.ObjectPath, i V) (*T, error) {
+			return constructor(p, i)
+		}, i)
+}
+
+func GetObjectsByInterfaceContext[T any, V any](ctx context.Context, conn *dbus.Conn, service string,
+	interfaceName string, constructor ObjectConstructorContext[T, V], i V) ([]*T, error) {
+
+	objects, err := GetManagedObjectsContext(ctx, conn, service)
 	if err != nil {
 		return nil, err
 	}
@@ -46,7 +61,7 @@ func GetObjectsByInterface[T any, V any](conn *dbus.Conn, service string, interf
 	for p, v := range objects {
 		for r := range v {
 			if r == interfaceName {
-				obj, err := constructor(p, i)
+				obj, err := constructor(ctx, p, i)
 				if err != nil {
 					return nil, err
 				}
@@ -58,8 +73,14 @@ func GetObjectsByInterface[T any, V any](conn *dbus.Conn, service string, interf
 }
 
 func CallMethod(conn *dbus.Conn, service string, path dbus.ObjectPath, method string, args ...interface{}) (*dbus.Call, error) {
+	return CallMethodContext(context.Background(), conn, service, path, method, args...)
+}
+
+func CallMethodContext(ctx context.Context, conn *dbus.Conn, service string, path dbus.ObjectPath,
+	method string, args ...interface{}) (*dbus.Call, error) {
+
 	obj := conn.Object(service, path)
-	if call := obj.Call(method, 0, args...); call.Err == nil {
+	if call := obj.CallWithContext(ctx, method, 0, args...); call.Err == nil {
 		return call, nil
 	} else {
 		return nil, call.Err
@@ -67,7 +88,11 @@ func CallMethod(conn *dbus.Conn, service string, path dbus.ObjectPath, method st
 }
 
 func GetManagedObjects(conn *dbus.Conn, service string) (DBusRetValues, error) {
-	call, err := CallMethod(conn, service, "/", callGetManagedObjects)
+	return GetManagedObjectsContext(context.Background(), conn, service)
+}
+
+func GetManagedObjectsContext(ctx context.Context, conn *dbus.Conn, service string) (DBusRetValues, error) {
+	call, err := CallMethodContext(ctx, conn, service, "/", callGetManagedObjects)
 	if err != nil {
 		return nil, err
 	}
@@ -79,7 +104,13 @@ func GetManagedObjects(conn *dbus.Conn, service string) (DBusRetValues, error) {
 }
 
 func GetAllProperties(conn *dbus.Conn, service string, path dbus.ObjectPath, iface string) (DBusMapVariant, error) {
-	call, err := CallMethod(conn, service, path, callPropertiesGetAll, iface)
+	return GetAllPropertiesContext(context.Background(), conn, service, path, iface)
+}
+
+func GetAllPropertiesContext(ctx context.Context, conn *dbus.Conn, service string, path dbus.ObjectPath,
+	iface string) (DBusMapVariant, error) {
+
+	call, err := CallMethodContext(ctx, conn, service, path, callPropertiesGetAll, iface)
 	if err != nil {
 		return nil, err
 	}
@@ -89,3 +120,17 @@ func GetAllProperties(conn *dbus.Conn, service string, path dbus.ObjectPath, ifa
 	}
 	return objects, nil
 }
+
+func SetProperty(conn *dbus.Conn, service string, path dbus.ObjectPath, iface string, name string, value interface{}) error {
+	return SetPropertyContext(context.Background(), conn, service, path, iface, name, value)
+}
+
+func SetPropertyContext(ctx context.Context, conn *dbus.Conn, service string, path dbus.ObjectPath,
+	iface string, name string, value interface{}) error {
+
+	if _, err := CallMethodContext(ctx, conn, service, path, callPropertiesSet, iface, name,
+		dbus.MakeVariant(value)); err != nil {
+		return err
+	}
+	return nil
+}
@@ -1,6 +1,9 @@
 package iwd
 
 import (
+	"context"
+	"time"
+
 	"github.com/godbus/dbus/v5"
 )
 
@@ -10,18 +13,22 @@ const (
 	callWSCPushButton  = iwdWSCIface + ".PushButton"
 	callWSCGeneratePin = iwdWSCIface + ".GeneratePin"
 	callWSCStartPin    = iwdWSCIface + ".StartPin"
-	callWSCCancel      = iwdStationIface + ".Cancel"
+	callWSCCancel      = iwdWSCIface + ".Cancel"
+
+	// How long to wait for iwd to acknowledge the Cancel
+	// issued when a PushButton or StartPin context is done.
+	wscCancelTimeout = 5 * time.Second
 )
 
 type WSC struct {
 	Path dbus.ObjectPath // /net/connman/iwd/{phy0,phy1,...}/{1,2,...}
-	iwd  *Iwd
+	object
 }
 
 func NewWSC(p dbus.ObjectPath, i *Iwd) (*WSC, error) {
 	return &WSC{
-		Path: p,
-		iwd:  i,
+		Path:   p,
+		object: i.object(),
 	}, nil
 }
 
@@ -40,7 +47,15 @@ func NewWSC(p dbus.ObjectPath, i *Iwd) (*WSC, error) {
 // completed and the network or the P2P peer has been
 // successfully connected.
 func (w *WSC) PushButton() error {
-	if _, err := w.iwd.CallServiceMethod(w.Path, callWSCPushButton); err != nil {
+	return w.PushButtonContext(context.Background())
+}
+
+// PushButtonContext is the context-aware variant of PushButton.
+// If ctx is done before the configuration completes, the
+// ongoing WSC operation is canceled.
+func (w *WSC) PushButtonContext(ctx context.Context) error {
+	if _, err := w.callMethod(ctx, w.Path, callWSCPushButton); err != nil {
+		w.cancelIfDone(ctx)
 		return err
 	}
 	return nil
@@ -49,15 +64,20 @@ func (w *WSC) PushButton() error {
 // Generates a random 8 digit PIN with an included check
 // digit suitable for use by most user interfaces.
 func (w *WSC) GeneratePin() (string, error) {
-	call, err := w.iwd.CallServiceMethod(w.Path, callWSCGeneratePin)
+	return w.GeneratePinContext(context.Background())
+}
+
+// GeneratePinContext is the context-aware variant of GeneratePin.
+func (w *WSC) GeneratePinContext(ctx context.Context) (string, error) {
+	call, err := w.callMethod(ctx, w.Path, callWSCGeneratePin)
 	if err != nil {
 		return "", err
 	}
-	var obj dbus.Variant
-	if err := call.Store(&obj); err != nil {
+	var pin string
+	if err := call.Store(&pin); err != nil {
 		return "", err
 	}
-	return obj.Value().(string), nil
+	return pin, nil
 }
 
 // Start WSC or connect to a specific P2P peer in PIN
@@ -76,7 +96,15 @@ func (w *WSC) GeneratePin() (string, error) {
 // completed and the network or the P2P peer has been
 // successfully connected.
 func (w *WSC) StartPin(pin string) error {
-	if _, err := w.iwd.CallServiceMethod(w.Path, callWSCStartPin, pin); err != nil {
+	return w.StartPinContext(context.Background(), pin)
+}
+
+// StartPinContext is the context-aware variant of StartPin.
+// If ctx is done before the configuration completes, the
+// ongoing WSC operation is canceled.
+func (w *WSC) StartPinContext(ctx context.Context, pin string) error {
+	if _, err := w.callMethod(ctx, w.Path, callWSCStartPin, pin); err != nil {
+		w.cancelIfDone(ctx)
 		return err
 	}
 	return nil
@@ -86,8 +114,25 @@ func (w *WSC) StartPin(pin string) error {
 // If no operation is ongoing, net.connman.iwd.NotAvailable
 // is returned.
 func (w *WSC) Cancel() error {
-	if _, err := w.iwd.CallServiceMethod(w.Path, callWSCCancel); err != nil {
+	return w.CancelContext(context.Background())
+}
+
+// CancelContext is the context-aware variant of Cancel.
+func (w *WSC) CancelContext(ctx context.Context) error {
+	if _, err := w.callMethod(ctx, w.Path, callWSCCancel); err != nil {
 		return err
 	}
 	return nil
 }
+
+// cancelIfDone aborts the WSC operation when the call
+// failed because ctx is done, since iwd keeps running it
+// after the caller stopped waiting for the reply.
+func (w *WSC) cancelIfDone(ctx context.Context) {
+	if ctx.Err() == nil {
+		return
+	}
+	cctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), wscCancelTimeout)
+	defer cancel()
+	w.CancelContext(cctx)
+}